@@ -0,0 +1,64 @@
+package conrate
+
+import (
+	"errors"
+	"time"
+)
+
+// RetryAfterError is returned by a task function when upstream asked to retry later, e.g. an HTTP 429 with a
+// Retry-After header. The executor backs its limiter off for After, so every task slows down, and requeues the
+// params until they run without RetryAfterError, the task stops or they were requeued WithMaxRequeues times.
+type RetryAfterError struct {
+	After time.Duration
+	// Err is the upstream error, it may be nil
+	Err error
+}
+
+func (r *RetryAfterError) Error() string {
+	if r.Err == nil {
+		return "retry after " + r.After.String()
+	}
+	return "retry after " + r.After.String() + ": " + r.Err.Error()
+}
+
+func (r *RetryAfterError) Unwrap() error {
+	return r.Err
+}
+
+// RetryAfter returns a *RetryAfterError asking the executor to retry the param after d
+func RetryAfter(d time.Duration) error {
+	return &RetryAfterError{After: d}
+}
+
+// retryAfter returns the *RetryAfterError in err, nil if there is none
+func retryAfter(err error) *RetryAfterError {
+	var retry *RetryAfterError
+	if errors.As(err, &retry) {
+		return retry
+	}
+	return nil
+}
+
+// requeue backs the limiter off for retry.After and frees the concurrency slots and the worker of j meanwhile, then
+// takes the limits of its task again to run it. Params whose task stops in the meantime are left to finish as canceled.
+func (e *Executor[T]) requeue(j job[T], retry *RetryAfterError) {
+	task := j.task
+	task.lc.limiter.Backoff(retry.After)
+	task.counter.addRequeued(int64(len(j.params)))
+	e.release(j.cost, j.idle)
+	j.requeues++
+	j.wg.Go(func() {
+		timer := time.NewTimer(retry.After)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			var ok bool
+			if j.cost, ok = j.acquire(j.params); ok && e.spawn(j) {
+				return
+			}
+		case <-task.lc.stop:
+		case <-task.ctx.Done():
+		}
+		e.resolve(task, j.index, e.stopCause(task))
+	})
+}
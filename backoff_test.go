@@ -0,0 +1,160 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRetryAfter expects, when a param fails with RetryAfter:
+//   - the param to run again once the delay passed and to complete without error;
+//   - params of other tasks to be held back for the delay;
+//   - Counter.Requeued to count the requeue.
+func TestRetryAfter(t *testing.T) {
+	p := NewRateLimitExecutor[int](100)
+	defer p.Stop()
+
+	const delay = 300 * time.Millisecond
+	var calls []time.Time
+	var mu sync.Mutex
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFuncE(func(context.Context, int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, time.Now())
+		if len(calls) == 1 {
+			return RetryAfter(delay)
+		}
+		return nil
+	}).BuildTask(ints(1)))
+	waitUntil(t, 3*time.Second, func() bool { return p.Counter().Requeued() == 1 })
+	if !p.IsBackingOff() {
+		t.Fatal("expected executor backing off")
+	}
+
+	var others []time.Time
+	other := p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		others = append(others, time.Now())
+	}).BuildTask(ints(3)))
+	if err := f.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	if err := other.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 2 {
+		t.Fatalf("task function called %d times, want 2", len(calls))
+	}
+	if got := calls[1].Sub(calls[0]); got < delay {
+		t.Fatalf("param retried after %v, want >= %v", got, delay)
+	}
+	for _, at := range others {
+		if got := at.Sub(calls[0]); got < delay-50*time.Millisecond {
+			t.Fatalf("other task ran %v after RetryAfter, want >= %v", got, delay)
+		}
+	}
+	if got := f.Counter().Completed(); got != 1 {
+		t.Fatalf("Completed() = %d, want 1", got)
+	}
+	if got := f.Counter().Failed(); got != 0 {
+		t.Fatalf("Failed() = %d, want 0", got)
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
+
+// TestRetryAfterCancel expects a param waiting to be retried to be canceled with its task.
+func TestRetryAfterCancel(t *testing.T) {
+	p := NewConcurrentExecutor[int](10)
+	defer p.Stop()
+
+	var calls atomic.Int64
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFuncE(func(context.Context, int) error {
+		calls.Add(1)
+		return &RetryAfterError{After: time.Hour, Err: errors.New("too many requests")}
+	}).BuildTask(ints(1)))
+	waitUntil(t, 3*time.Second, func() bool { return p.Counter().Requeued() == 1 })
+	if got := p.Counter().Pending(); got != 1 {
+		t.Fatalf("Pending() = %d, want 1", got)
+	}
+
+	f.Cancel()
+	if err := f.WaitTimeout(3 * time.Second); !errors.Is(err, context.Canceled) {
+		t.Fatalf("WaitTimeout() = %v, want context.Canceled", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("task function called %d times, want 1", got)
+	}
+	if got := p.Counter().Canceled(); got != 1 {
+		t.Fatalf("Canceled() = %d, want 1", got)
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
+
+// TestRetryAfterReleases expects a param waiting to be retried to hold neither its concurrency slot nor its worker,
+// another param taking both meanwhile.
+func TestRetryAfterReleases(t *testing.T) {
+	p := NewConcurrentExecutor[int](1, WithWorkers(1))
+	defer p.Stop()
+
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFuncE(func(context.Context, int) error {
+		return RetryAfter(time.Hour)
+	}).BuildTask(ints(1)))
+	defer f.Cancel()
+	waitUntil(t, 3*time.Second, func() bool { return p.Counter().Requeued() == 1 })
+	if !p.idle.TryAcquire(1) {
+		t.Fatal("concurrency slot held by the param waiting to be retried")
+	}
+	p.idle.Release(1)
+
+	ran := make(chan struct{})
+	other := NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		close(ran)
+	}).BuildTask(ints(1))
+	other.counter = newCounter(p.counter)
+	other.lc = p.current()
+	canceled := new(atomic.Int64)
+	canceled.Store(1)
+	wg := new(sync.WaitGroup)
+	p.spawn(job[int]{task: other, params: other.param, canceled: canceled, wg: wg})
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("worker held by the param waiting to be retried")
+	}
+	wg.Wait()
+}
+
+// TestMaxRequeues expects a param failing with RetryAfter to be requeued WithMaxRequeues times, then to fail with
+// its *RetryAfterError.
+func TestMaxRequeues(t *testing.T) {
+	p := NewRateLimitExecutor[int](100, WithMaxRequeues(2))
+	defer p.Stop()
+
+	var calls atomic.Int64
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFuncE(func(context.Context, int) error {
+		calls.Add(1)
+		return RetryAfter(10 * time.Millisecond)
+	}).BuildTask(ints(1)))
+	err := f.WaitTimeout(5 * time.Second)
+	var retry *RetryAfterError
+	if !errors.As(err, &retry) {
+		t.Fatalf("WaitTimeout() = %v, want *RetryAfterError", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("task function called %d times, want 3", got)
+	}
+	if got := f.Counter().Requeued(); got != 2 {
+		t.Fatalf("Requeued() = %d, want 2", got)
+	}
+	if got := f.Counter().Failed(); got != 1 {
+		t.Fatalf("Failed() = %d, want 1", got)
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
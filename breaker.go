@@ -0,0 +1,300 @@
+package conrate
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit open")
+
+type BreakerState int64
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig configures the circuit breaker of an executor, see WithCircuitBreaker.
+// A closed breaker opens once at least MinRequests outcomes were recorded within Window and the rate of failed
+// or slow outcomes reaches FailureRate or SlowRate. An open breaker turns half-open after OpenTimeout and lets
+// Probes params through, it closes if they all succeed and opens again on the first failure.
+type BreakerConfig struct {
+	// Window is the duration outcomes are counted over, 10s by default
+	Window time.Duration
+	// MinRequests is the number of outcomes within Window needed to open, 10 by default
+	MinRequests int
+	// FailureRate opens the breaker when reached by failed outcomes, 0.5 by default
+	FailureRate float64
+	// SlowCall is the duration after which a run is slow, 0 disables SlowRate
+	SlowCall time.Duration
+	// SlowRate opens the breaker when reached by slow outcomes, 1 by default
+	SlowRate float64
+	// OpenTimeout is how long the breaker stays open, 30s by default
+	OpenTimeout time.Duration
+	// Probes is the number of params let through half-open, 1 by default
+	Probes int
+	// ShortCircuit fails params with ErrCircuitOpen while the breaker is open instead of holding them back
+	ShortCircuit bool
+	// OnStateChange is called in order on every state change with the key of the breaker, "" for the executor breaker
+	OnStateChange func(key string, from, to BreakerState)
+}
+
+func (c BreakerConfig) withDefaults() BreakerConfig {
+	if c.Window <= 0 {
+		c.Window = 10 * time.Second
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = 10
+	}
+	if c.FailureRate <= 0 {
+		c.FailureRate = 0.5
+	}
+	if c.SlowRate <= 0 {
+		c.SlowRate = 1
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = 30 * time.Second
+	}
+	if c.Probes <= 0 {
+		c.Probes = 1
+	}
+	return c
+}
+
+const breakerBuckets = 10
+
+// bucket counts the outcomes of a slice of the breaker window
+type bucket struct {
+	start               time.Time
+	total, failed, slow int
+}
+
+// breaker is the circuit breaker of an executor or of one key
+type breaker struct {
+	key     string
+	config  *BreakerConfig
+	state   BreakerState
+	buckets [breakerBuckets]bucket
+	// probes is the number of probes let through, succeeded the number of probes which succeeded
+	probes    int
+	succeeded int
+	changed   chan struct{}
+	// changes are the state changes not handed to OnStateChange yet, delivering is true while a goroutine hands them
+	changes    [][2]BreakerState
+	delivering bool
+	mu         sync.Mutex
+}
+
+// admit tells if a param may run now, wait is closed on the next state change when it may not.
+// A half-open breaker counts the param as a probe.
+func (b *breaker) admit() (ok bool, wait <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerClosed:
+		return true, nil
+	case BreakerHalfOpen:
+		if b.probes < b.config.Probes {
+			b.probes++
+			return true, nil
+		}
+	}
+	return false, b.changed
+}
+
+// cancel gives back a probe admitted but never run
+func (b *breaker) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen && b.probes > 0 {
+		b.probes--
+		b.notify()
+	}
+}
+
+// record counts the outcomes of n params which ran for elapsed, failed of them failed, and returns true if the
+// breaker opened. A batch counts an outcome per param.
+func (b *breaker) record(n, failed int, elapsed time.Duration) bool {
+	slow := b.config.SlowCall > 0 && elapsed >= b.config.SlowCall
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerHalfOpen:
+		if failed > 0 || slow {
+			b.transit(BreakerOpen)
+			return true
+		}
+		if b.succeeded++; b.succeeded >= b.config.Probes {
+			b.transit(BreakerClosed)
+		} else {
+			b.notify()
+		}
+	case BreakerClosed:
+		now := time.Now()
+		width := b.config.Window / breakerBuckets
+		slot := now.UnixNano() / int64(width)
+		current := &b.buckets[slot%breakerBuckets]
+		if now.Sub(current.start) >= width {
+			*current = bucket{start: time.Unix(0, slot*int64(width))}
+		}
+		current.total += n
+		current.failed += failed
+		if slow {
+			current.slow += n
+		}
+		var total, failed, slowed int
+		for _, bucket := range b.buckets {
+			if now.Sub(bucket.start) < b.config.Window {
+				total += bucket.total
+				failed += bucket.failed
+				slowed += bucket.slow
+			}
+		}
+		if total >= b.config.MinRequests &&
+			(float64(failed) >= b.config.FailureRate*float64(total) ||
+				(b.config.SlowCall > 0 && float64(slowed) >= b.config.SlowRate*float64(total))) {
+			b.transit(BreakerOpen)
+			return true
+		}
+	}
+	return false
+}
+
+// transit changes the state, b.mu must be held
+func (b *breaker) transit(to BreakerState) {
+	from := b.state
+	b.state = to
+	b.probes, b.succeeded = 0, 0
+	switch to {
+	case BreakerOpen:
+		time.AfterFunc(b.config.OpenTimeout, func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if b.state == BreakerOpen {
+				b.transit(BreakerHalfOpen)
+			}
+		})
+	case BreakerClosed:
+		b.buckets = [breakerBuckets]bucket{}
+	}
+	b.notify()
+	if b.config.OnStateChange != nil {
+		b.changes = append(b.changes, [2]BreakerState{from, to})
+		if !b.delivering {
+			b.delivering = true
+			go b.deliver()
+		}
+	}
+}
+
+// deliver hands state changes to OnStateChange in order, outside of b.mu
+func (b *breaker) deliver() {
+	for {
+		b.mu.Lock()
+		changes := b.changes
+		b.changes = nil
+		if len(changes) == 0 {
+			b.delivering = false
+		}
+		b.mu.Unlock()
+		if len(changes) == 0 {
+			return
+		}
+		for _, change := range changes {
+			b.config.OnStateChange(b.key, change[0], change[1])
+		}
+	}
+}
+
+// notify wakes up params held back, b.mu must be held
+func (b *breaker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func (b *breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// breakers holds the executor breaker and the breakers of keys
+type breakers struct {
+	config BreakerConfig
+	keys   map[string]*breaker
+	mu     sync.Mutex
+}
+
+func (b *breakers) get(key string) *breaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	br, ok := b.keys[key]
+	if !ok {
+		br = &breaker{key: key, config: &b.config, changed: make(chan struct{})}
+		b.keys[key] = br
+	}
+	return br
+}
+
+func newBreakers(config *BreakerConfig) *breakers {
+	if config == nil {
+		return nil
+	}
+	return &breakers{config: config.withDefaults(), keys: make(map[string]*breaker)}
+}
+
+// breaker returns the breaker of params, nil if the executor has no circuit breaker
+func (e *Executor[T]) breaker(task *Task[T], params []T) *breaker {
+	if e.breakers == nil {
+		return nil
+	}
+	if task.breakerKey == nil {
+		return e.breakers.get("")
+	}
+	return e.breakers.get(task.breakerKey(params[0]))
+}
+
+// admit waits until the breaker of params lets them run, it fails with ErrCircuitOpen if the breaker short-circuits
+// or with the reason the task stopped
+func (e *Executor[T]) admit(task *Task[T], b *breaker) error {
+	for {
+		ok, wait := b.admit()
+		if ok {
+			return nil
+		}
+		if b.config.ShortCircuit {
+			return ErrCircuitOpen
+		}
+		select {
+		case <-wait:
+		case <-task.lc.stop:
+			return ErrExecutorStopped
+		case <-task.ctx.Done():
+			return e.stopCause(task)
+		}
+	}
+}
+
+// BreakerState returns the state of the circuit breaker of key, "" is the executor breaker used by tasks without
+// TaskBuilder.WithBreakerKey. It is BreakerClosed without WithCircuitBreaker.
+func (e *Executor[T]) BreakerState(key string) BreakerState {
+	if e.breakers == nil {
+		return BreakerClosed
+	}
+	return e.breakers.get(key).State()
+}
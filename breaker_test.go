@@ -0,0 +1,194 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// transitions records breaker state changes.
+type transitions struct {
+	states []BreakerState
+	mu     sync.Mutex
+}
+
+func (t *transitions) record(_ string, _, to BreakerState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.states = append(t.states, to)
+}
+
+func (t *transitions) get() []BreakerState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.states)
+}
+
+// sequential runs the params of a task one at a time, a param is admitted by the breaker once the previous one
+// started so at most one param slips through after the breaker opens.
+func sequential[T any]() *TaskBuilder[T] {
+	return NewTaskBuilder[T]().WithMaxConcurrency(1)
+}
+
+// TestBreaker expects:
+//   - the breaker to open once MinRequests outcomes reach FailureRate and to hold back the other params;
+//   - the breaker to turn half-open after OpenTimeout and close after a succeeded probe;
+//   - held back params to run once closed, state changes to reach OnStateChange and Counter.Trips.
+func TestBreaker(t *testing.T) {
+	seen := new(transitions)
+	p := NewConcurrentExecutor[int](100, WithCircuitBreaker(BreakerConfig{
+		MinRequests:   4,
+		OpenTimeout:   200 * time.Millisecond,
+		OnStateChange: seen.record,
+	}))
+	defer p.Stop()
+
+	var fail atomic.Bool
+	var calls atomic.Int64
+	fail.Store(true)
+	errDown := errors.New("down")
+	f := p.Submit(sequential[int]().WithTaskFuncE(func(context.Context, int) error {
+		calls.Add(1)
+		if fail.Load() {
+			return errDown
+		}
+		return nil
+	}).BuildTask(ints(10)))
+
+	waitUntil(t, 3*time.Second, func() bool { return p.BreakerState("") == BreakerOpen })
+	time.Sleep(50 * time.Millisecond)
+	opened := calls.Load()
+	if opened > 5 {
+		t.Fatalf("task function called %d times before open, want <= 5", opened)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := calls.Load(); got != opened {
+		t.Fatalf("task function called %d times while open, want %d", got, opened)
+	}
+	fail.Store(false)
+	if err := f.WaitTimeout(5 * time.Second); !errors.Is(err, errDown) {
+		t.Fatalf("WaitTimeout() = %v, want errDown", err)
+	}
+	if got := f.Counter().Failed(); got != opened {
+		t.Fatalf("Failed() = %d, want %d", got, opened)
+	}
+	if got := f.Counter().Completed(); got != 10 {
+		t.Fatalf("Completed() = %d, want 10", got)
+	}
+	if got := p.Counter().Trips(); got != 1 {
+		t.Fatalf("Trips() = %d, want 1", got)
+	}
+	want := []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerClosed}
+	waitUntil(t, 3*time.Second, func() bool { return slices.Equal(seen.get(), want) })
+}
+
+// TestBreakerReopen expects a failed probe to open the half-open breaker again.
+func TestBreakerReopen(t *testing.T) {
+	seen := new(transitions)
+	p := NewConcurrentExecutor[int](100, WithCircuitBreaker(BreakerConfig{
+		MinRequests:   2,
+		OpenTimeout:   100 * time.Millisecond,
+		OnStateChange: seen.record,
+	}))
+	defer p.Stop()
+
+	f := p.Submit(sequential[int]().WithTaskFuncE(func(context.Context, int) error {
+		return errors.New("down")
+	}).BuildTask(ints(10)))
+	want := []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerOpen}
+	waitUntil(t, 3*time.Second, func() bool { return len(seen.get()) >= 3 })
+	if got := seen.get()[:3]; !slices.Equal(got, want) {
+		t.Fatalf("states = %v, want %v", got, want)
+	}
+	f.Cancel()
+	f.Wait()
+	assertCounterZeroPending(t, p.Counter())
+}
+
+// TestBreakerShortCircuit expects:
+//   - ShortCircuit to fail params with ErrCircuitOpen without running them while open;
+//   - a breaker per key with WithBreakerKey, a failing key not affecting the others.
+func TestBreakerShortCircuit(t *testing.T) {
+	p := NewConcurrentExecutor[string](100, WithCircuitBreaker(BreakerConfig{
+		MinRequests:  2,
+		OpenTimeout:  time.Hour,
+		ShortCircuit: true,
+	}))
+	defer p.Stop()
+
+	var calls atomic.Int64
+	f := p.Submit(sequential[string]().
+		WithBreakerKey(identity).
+		WithTaskFuncE(func(_ context.Context, s string) error {
+			calls.Add(1)
+			if s == "bad" {
+				return errors.New("bad")
+			}
+			return nil
+		}).
+		BuildTask([]string{"bad", "bad", "bad", "good", "bad", "good"}))
+	f.Wait()
+
+	// good runs twice, bad twice before open and maybe once more while the second bad runs
+	shorted := f.Counter().ShortCircuited()
+	if shorted < 1 || calls.Load()+shorted != 6 {
+		t.Fatalf("%d calls and %d params short-circuited, want 6 in all with at least 1 short-circuited", calls.Load(), shorted)
+	}
+	if got := f.Counter().Failed(); got != 4 {
+		t.Fatalf("Failed() = %d, want 4", got)
+	}
+	if p.BreakerState("bad") != BreakerOpen || p.BreakerState("good") != BreakerClosed {
+		t.Fatalf("states bad %v good %v, want open and closed", p.BreakerState("bad"), p.BreakerState("good"))
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
+
+// TestBreakerSlowCalls expects slow runs to open the breaker once they reach SlowRate.
+func TestBreakerSlowCalls(t *testing.T) {
+	p := NewConcurrentExecutor[int](100, WithCircuitBreaker(BreakerConfig{
+		MinRequests:  2,
+		SlowCall:     20 * time.Millisecond,
+		SlowRate:     0.5,
+		OpenTimeout:  time.Hour,
+		ShortCircuit: true,
+	}))
+	defer p.Stop()
+
+	f := p.Submit(sequential[int]().WithTaskFunc(func(context.Context, int) {
+		time.Sleep(30 * time.Millisecond)
+	}).BuildTask(ints(5)))
+	f.Wait()
+	if p.BreakerState("") != BreakerOpen {
+		t.Fatalf("state %v, want open", p.BreakerState(""))
+	}
+	if got := f.Counter().ShortCircuited(); got == 0 {
+		t.Fatal("expected params short-circuited after slow runs")
+	}
+}
+
+// TestBreakerBatch expects the items of failed batches to count as failed outcomes, a plain error failing every
+// item and a *BatchError the items it maps.
+func TestBreakerBatch(t *testing.T) {
+	for name, err := range map[string]error{
+		"plain":      errors.New("down"),
+		"BatchError": &BatchError{Errs: map[int]error{0: errors.New("down"), 1: errors.New("down")}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := NewConcurrentExecutor[int](100, WithCircuitBreaker(BreakerConfig{MinRequests: 2, OpenTimeout: time.Hour}))
+			defer p.Stop()
+			f := p.Submit(sequential[int]().WithBatching(2, 0, func(context.Context, []int) error {
+				return err
+			}).BuildTask(ints(40)))
+			waitUntil(t, 3*time.Second, func() bool { return p.BreakerState("") == BreakerOpen })
+			f.Cancel()
+			f.Wait()
+			if got := p.Counter().Trips(); got != 1 {
+				t.Fatalf("Trips() = %d, want 1", got)
+			}
+		})
+	}
+}
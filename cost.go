@@ -0,0 +1,54 @@
+package conrate
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrCostExceedsCapacity = errors.New("cost exceeds capacity")
+
+// cost is the number of rate tokens or concurrency slots params take, the sum of their cost with WithCost else 1
+func (t *Task[T]) cost(params []T) int {
+	if t.costFunc == nil {
+		return 1
+	}
+	cost := 0
+	for _, param := range params {
+		cost += max(t.costFunc(param), 1)
+	}
+	return cost
+}
+
+// fits fails with ErrCostExceedsCapacity if params cost more than the executor capacity, they would never run
+func (e *Executor[T]) fits(task *Task[T], params []T) error {
+	if task.costFunc == nil {
+		return nil
+	}
+	if cost, capacity := task.cost(params), task.lc.limiter.Capacity(); cost > capacity {
+		return fmt.Errorf("%w: cost %d, capacity %d", ErrCostExceedsCapacity, cost, capacity)
+	}
+	return nil
+}
+
+// tokens receives n tokens of task, false if the task stopped first. Each token taken makes room for the scheduler.
+func (e *Executor[T]) tokens(task *Task[T], n int) bool {
+	for range n {
+		// a buffered token must not win over a stop
+		select {
+		case <-task.lc.stop:
+			return false
+		case <-task.ctx.Done():
+			return false
+		default:
+		}
+		select {
+		case <-task.lc.stop:
+			return false
+		case <-task.ctx.Done():
+			return false
+		case <-task.wait:
+			task.lc.signal()
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCostRateLimit expects params WithCost to take cost tokens: 5 params costing 5 at 10 qps take >= 1s once the
+// 10 token burst is used.
+func TestCostRateLimit(t *testing.T) {
+	p := NewRateLimitExecutor[int](10)
+	defer p.Stop()
+
+	start := time.Now()
+	f := p.Submit(NewTaskBuilder[int]().
+		WithCost(func(int) int { return 5 }).
+		WithTaskFunc(func(context.Context, int) {}).
+		BuildTask(ints(5)))
+	if err := f.WaitTimeout(10 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	if got := time.Since(start); got < time.Second {
+		t.Fatalf("5 params costing 5 ran in %v at 10 qps, want >= 1s", got)
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
+
+// TestCostConcurrency expects params WithCost to take cost concurrency slots: at most 2 params costing 5 run at
+// once with capacity 10.
+func TestCostConcurrency(t *testing.T) {
+	p := NewConcurrentExecutor[int](10)
+	defer p.Stop()
+
+	var running, maxRunning atomic.Int64
+	f := p.Submit(NewTaskBuilder[int]().
+		WithCost(func(int) int { return 5 }).
+		WithTaskFunc(func(context.Context, int) {
+			r := running.Add(1)
+			for {
+				m := maxRunning.Load()
+				if r <= m || maxRunning.CompareAndSwap(m, r) {
+					break
+				}
+			}
+			time.Sleep(300 * time.Millisecond)
+			running.Add(-1)
+		}).
+		BuildTask(ints(4)))
+	if err := f.WaitTimeout(10 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	if got := maxRunning.Load(); got != 2 {
+		t.Fatalf("max running %d, want 2", got)
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
+
+// TestCostExceedsCapacity expects a param costing more than the capacity to fail with ErrCostExceedsCapacity
+// without running while the other params run, in both modes.
+func TestCostExceedsCapacity(t *testing.T) {
+	for _, mode := range []ExecutorMode{ConcurrencyMode, RateLimitMode} {
+		p := NewExecutor[int](10, mode)
+		var calls atomic.Int64
+		f := p.Submit(NewTaskBuilder[int]().
+			WithCost(func(i int) int { return i * 10 }).
+			WithTaskFunc(func(context.Context, int) { calls.Add(1) }).
+			BuildTask(ints(3)))
+		if err := f.WaitTimeout(5 * time.Second); !errors.Is(err, ErrCostExceedsCapacity) {
+			t.Fatalf("mode %d: WaitTimeout() = %v, want ErrCostExceedsCapacity", mode, err)
+		}
+		if got := calls.Load(); got != 2 {
+			t.Fatalf("mode %d: task function called %d times, want 2", mode, got)
+		}
+		if got := f.Counter().Failed(); got != 1 {
+			t.Fatalf("mode %d: Failed() = %d, want 1", mode, got)
+		}
+		assertCounterZeroPending(t, p.Counter())
+		p.Stop()
+	}
+}
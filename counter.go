@@ -0,0 +1,157 @@
+package conrate
+
+import "sync/atomic"
+
+// Counter counts params by state, a task Counter also counts into its parent executor Counter
+type Counter struct {
+	running   *atomic.Int64
+	pending   *atomic.Int64
+	completed *atomic.Int64
+	canceled  *atomic.Int64
+	failed    *atomic.Int64
+	shorted   *atomic.Int64
+	trips     *atomic.Int64
+	requeued  *atomic.Int64
+	expired   *atomic.Int64
+	parent    *Counter
+}
+
+func (c *Counter) Running() int64 {
+	return c.running.Load()
+}
+
+func (c *Counter) Pending() int64 {
+	return c.pending.Load()
+}
+
+func (c *Counter) Completed() int64 {
+	return c.completed.Load()
+}
+
+func (c *Counter) Canceled() int64 {
+	return c.canceled.Load()
+}
+
+// Failed counts completed params which returned an error or panicked, it is a part of Completed
+func (c *Counter) Failed() int64 {
+	return c.failed.Load()
+}
+
+// ShortCircuited counts params failed with ErrCircuitOpen without running, they are a part of Failed
+func (c *Counter) ShortCircuited() int64 {
+	return c.shorted.Load()
+}
+
+// Trips counts the times a circuit breaker opened on the outcome of a param
+func (c *Counter) Trips() int64 {
+	return c.trips.Load()
+}
+
+// Requeued counts the times params were requeued after failing with *RetryAfterError
+func (c *Counter) Requeued() int64 {
+	return c.requeued.Load()
+}
+
+// Expired counts params canceled because the deadline of their task passed before they started, they are a part of
+// Canceled
+func (c *Counter) Expired() int64 {
+	return c.expired.Load()
+}
+
+func (c *Counter) Reset() {
+	c.running.Store(0)
+	c.pending.Store(0)
+	c.completed.Store(0)
+	c.canceled.Store(0)
+	c.failed.Store(0)
+	c.shorted.Store(0)
+	c.trips.Store(0)
+	c.requeued.Store(0)
+	c.expired.Store(0)
+}
+
+func (c *Counter) addRunning(n int64) {
+	for ; c != nil; c = c.parent {
+		c.running.Add(n)
+	}
+}
+
+func (c *Counter) addPending(n int64) {
+	for ; c != nil; c = c.parent {
+		c.pending.Add(n)
+	}
+}
+
+func (c *Counter) addCompleted(n int64) {
+	for ; c != nil; c = c.parent {
+		c.completed.Add(n)
+	}
+}
+
+func (c *Counter) addCanceled(n int64) {
+	for ; c != nil; c = c.parent {
+		c.canceled.Add(n)
+	}
+}
+
+func (c *Counter) addFailed(n int64) {
+	for ; c != nil; c = c.parent {
+		c.failed.Add(n)
+	}
+}
+
+func (c *Counter) addShortCircuited(n int64) {
+	for ; c != nil; c = c.parent {
+		c.shorted.Add(n)
+	}
+}
+
+func (c *Counter) addTrips(n int64) {
+	for ; c != nil; c = c.parent {
+		c.trips.Add(n)
+	}
+}
+
+func (c *Counter) addRequeued(n int64) {
+	for ; c != nil; c = c.parent {
+		c.requeued.Add(n)
+	}
+}
+
+func (c *Counter) addExpired(n int64) {
+	for ; c != nil; c = c.parent {
+		c.expired.Add(n)
+	}
+}
+
+// sum returns a detached Counter holding the sum of counters
+func sum(counters ...*Counter) *Counter {
+	s := newCounter(nil)
+	for _, c := range counters {
+		s.running.Add(c.Running())
+		s.pending.Add(c.Pending())
+		s.completed.Add(c.Completed())
+		s.canceled.Add(c.Canceled())
+		s.failed.Add(c.Failed())
+		s.shorted.Add(c.ShortCircuited())
+		s.trips.Add(c.Trips())
+		s.requeued.Add(c.Requeued())
+		s.expired.Add(c.Expired())
+	}
+	return s
+}
+
+func newCounter(parent *Counter) *Counter {
+	return &Counter{
+		running:   new(atomic.Int64),
+		pending:   new(atomic.Int64),
+		completed: new(atomic.Int64),
+		canceled:  new(atomic.Int64),
+		failed:    new(atomic.Int64),
+		shorted:   new(atomic.Int64),
+		trips:     new(atomic.Int64),
+		requeued:  new(atomic.Int64),
+		expired:   new(atomic.Int64),
+		parent:    parent,
+	}
+}
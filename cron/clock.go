@@ -0,0 +1,19 @@
+package cron
+
+import "time"
+
+// Clock tells the time to a Scheduler, tests inject a fake one to control ticks
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,74 @@
+package cron
+
+import "time"
+
+// Overlap tells what a Job does when it is due while its previous run has not finished
+type Overlap int
+
+const (
+	// OverlapSkip records the run as skipped
+	OverlapSkip Overlap = iota
+	// OverlapQueue starts the run once the previous one finishes
+	OverlapQueue
+	// OverlapAllow starts the run right away
+	OverlapAllow
+)
+
+type Option func(*options)
+
+type options struct {
+	clock Clock
+}
+
+// WithClock replaces the wall clock of a Scheduler
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{clock: realClock{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+type JobOption func(*jobOptions)
+
+type jobOptions struct {
+	overlap Overlap
+	jitter  time.Duration
+	history int
+}
+
+// WithOverlap sets the overlap policy of a Job, OverlapSkip by default
+func WithOverlap(overlap Overlap) JobOption {
+	return func(o *jobOptions) {
+		o.overlap = overlap
+	}
+}
+
+// WithJitter delays every run by a random duration in [0, jitter), jitter should be shorter than the interval
+// between two activations, a tick whose jittered start overtakes the next activation swallows it
+func WithJitter(jitter time.Duration) JobOption {
+	return func(o *jobOptions) {
+		o.jitter = max(jitter, 0)
+	}
+}
+
+// WithHistory keeps the last size runs of a Job, 16 by default
+func WithHistory(size int) JobOption {
+	return func(o *jobOptions) {
+		o.history = max(size, 1)
+	}
+}
+
+func newJobOptions(opts ...JobOption) *jobOptions {
+	o := &jobOptions{overlap: OverlapSkip, history: 16}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
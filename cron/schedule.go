@@ -0,0 +1,227 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSchedule = errors.New("cron: invalid schedule")
+
+// Schedule returns the next activation time strictly after t, the zero time if there is none
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// Every is a Schedule firing every d, d is rounded up to a second and at least one second
+func Every(d time.Duration) Schedule {
+	d = max(d.Round(time.Second), time.Second)
+	return every(d)
+}
+
+// field is a bit set of the allowed values of one cron field
+type field uint64
+
+func (f field) has(v int) bool {
+	return f&(1<<uint(v)) != 0
+}
+
+// first is the smallest allowed value >= v, -1 if there is none
+func (f field) first(v int) int {
+	rest := f >> uint(v)
+	if rest == 0 {
+		return -1
+	}
+	return v + bits.TrailingZeros64(uint64(rest))
+}
+
+type bounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minutes = bounds{min: 0, max: 59}
+	hours   = bounds{min: 0, max: 23}
+	days    = bounds{min: 1, max: 31}
+	months  = bounds{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	weekdays = bounds{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronSchedule is a parsed five field cron expression, evaluated in the location of the time passed to Next
+type cronSchedule struct {
+	minute, hour, dom, month, dow field
+	// anyDom and anyDow tell a * day field, if both day fields are restricted a day matching either of them matches
+	anyDom, anyDow bool
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom, dow := c.dom.has(t.Day()), c.dow.has(int(t.Weekday()))
+	if c.anyDom || c.anyDow {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	loc := t.Location()
+	// every combination repeats within 5 years, a schedule like `0 0 30 2 *` (Feb 30) never matches and Next returns
+	// the zero time
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !c.month.has(int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.hour.has(t.Hour()) {
+			if h := c.hour.first(t.Hour()); h >= 0 {
+				t = time.Date(t.Year(), t.Month(), t.Day(), h, 0, 0, 0, loc)
+			} else {
+				t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			}
+			continue
+		}
+		if !c.minute.has(t.Minute()) {
+			if m := c.minute.first(t.Minute()); m >= 0 {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), m, 0, 0, loc)
+			} else {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			}
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Parse parses a standard five field cron expression (minute, hour, day of month, month, day of week), fields
+// support *, lists, ranges, steps and month or weekday names. The descriptors @yearly, @annually, @monthly,
+// @weekly, @daily, @midnight, @hourly and @every <duration> are accepted too.
+func Parse(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if d, ok := strings.CutPrefix(expr, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSchedule, expr)
+		}
+		return Every(interval), nil
+	}
+	if spec, ok := descriptors[strings.ToLower(expr)]; ok {
+		expr = spec
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q needs 5 fields", ErrInvalidSchedule, expr)
+	}
+	c := &cronSchedule{anyDom: fields[2] == "*", anyDow: fields[4] == "*"}
+	for i, b := range []bounds{minutes, hours, days, months, weekdays} {
+		f, err := parseField(fields[i], b)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidSchedule, expr, err)
+		}
+		switch i {
+		case 0:
+			c.minute = f
+		case 1:
+			c.hour = f
+		case 2:
+			c.dom = f
+		case 3:
+			c.month = f
+		case 4:
+			// 7 is sunday as well
+			if f.has(7) {
+				f |= 1
+			}
+			c.dow = f
+		}
+	}
+	return c, nil
+}
+
+// MustParse is Parse panicking on an invalid expression
+func MustParse(expr string) Schedule {
+	s, err := Parse(expr)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func parseField(s string, b bounds) (field, error) {
+	var f field
+	for part := range strings.SplitSeq(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+			step = n
+		}
+		lo, hi := b.min, b.max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = parseValue(from, b); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = parseValue(to, b); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = b.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			f |= 1 << uint(v)
+		}
+	}
+	return f, nil
+}
+
+func parseValue(s string, b bounds) (int, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < b.min || v > b.max {
+		return 0, fmt.Errorf("value %q out of range [%d, %d]", s, b.min, b.max)
+	}
+	return v, nil
+}
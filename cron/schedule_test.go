@@ -0,0 +1,53 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestParse expects Next of parsed expressions to return the next matching minute after from.
+func TestParse(t *testing.T) {
+	from := time.Date(2026, time.January, 30, 10, 17, 42, 0, time.UTC) // a friday
+	for _, c := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, time.January, 30, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, time.January, 30, 10, 30, 0, 0, time.UTC)},
+		{"5,10 9-11 * * *", time.Date(2026, time.January, 30, 11, 5, 0, 0, time.UTC)},
+		{"0 0 * * *", time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * mon-wed", time.Date(2026, time.February, 2, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2026, time.February, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * sat", time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, time.January, 30, 11, 0, 0, 0, time.UTC)},
+		{"@every 90s", from.Add(90 * time.Second)},
+	} {
+		s, err := Parse(c.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) = %v, want nil", c.expr, err)
+		}
+		if got := s.Next(from); !got.Equal(c.want) {
+			t.Errorf("Parse(%q).Next() = %v, want %v", c.expr, got, c.want)
+		}
+	}
+}
+
+// TestParseInvalid expects malformed expressions to fail with ErrInvalidSchedule.
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "@every x", "@every -1s", "0 0 * foo *"} {
+		if _, err := Parse(expr); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("Parse(%q) = %v, want ErrInvalidSchedule", expr, err)
+		}
+	}
+}
+
+// TestParseNever expects Next of an expression which never matches to return the zero time.
+func TestParseNever(t *testing.T) {
+	if got := MustParse("0 0 30 feb *").Next(time.Now()); !got.IsZero() {
+		t.Fatalf("Next() = %v, want zero time", got)
+	}
+}
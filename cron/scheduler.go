@@ -0,0 +1,263 @@
+package cron
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/riete/conrate"
+)
+
+var (
+	ErrDuplicateJob     = errors.New("cron: duplicate job name")
+	ErrSchedulerStopped = errors.New("cron: scheduler stopped")
+)
+
+// Submitter is the part of conrate.Executor a Scheduler submits to
+type Submitter[T any] interface {
+	Submit(tasks ...*conrate.Task[T]) *conrate.Future
+}
+
+// Run is one activation of a Job
+type Run struct {
+	// Scheduled is the activation time, before jitter
+	Scheduled time.Time
+	// Started is when the task was submitted, zero for a skipped run
+	Started time.Time
+	// Finished is when Future was done, zero while running
+	Finished time.Time
+	// Skipped tells a run dropped by OverlapSkip
+	Skipped bool
+	Err     error
+	Future  *conrate.Future
+}
+
+// Job builds and submits a fresh task on every activation of its schedule
+type Job[T any] struct {
+	name      string
+	schedule  Schedule
+	builder   *conrate.TaskBuilder[T]
+	params    func(scheduled time.Time) []T
+	submitter Submitter[T]
+	clock     Clock
+	options   *jobOptions
+	running   int
+	queued    []time.Time
+	history   []*Run
+	stop      chan struct{}
+	stopped   bool
+	mu        sync.Mutex
+}
+
+func (j *Job[T]) Name() string {
+	return j.name
+}
+
+// Running is the number of runs whose Future is not done
+func (j *Job[T]) Running() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.running
+}
+
+// History returns the last runs, oldest first
+func (j *Job[T]) History() []Run {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	runs := make([]Run, 0, len(j.history))
+	for _, run := range j.history {
+		runs = append(runs, *run)
+	}
+	return runs
+}
+
+// Stop stops scheduling new runs and drops queued ones, runs already submitted are left to the executor
+func (j *Job[T]) Stop() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.stopped {
+		return
+	}
+	j.stopped = true
+	j.queued = nil
+	close(j.stop)
+}
+
+func (j *Job[T]) jitter() time.Duration {
+	if j.options.jitter <= 0 {
+		return 0
+	}
+	return rand.N(j.options.jitter)
+}
+
+func (j *Job[T]) loop() {
+	now := j.clock.Now()
+	next := j.schedule.Next(now)
+	for !next.IsZero() {
+		select {
+		case <-j.stop:
+			return
+		case <-j.clock.After(next.Sub(now) + j.jitter()):
+		}
+		j.tick(next)
+		// activations missed while waiting are skipped
+		now = j.clock.Now()
+		for next = j.schedule.Next(next); !next.IsZero() && !next.After(now); {
+			next = j.schedule.Next(next)
+		}
+	}
+}
+
+func (j *Job[T]) tick(scheduled time.Time) {
+	j.mu.Lock()
+	if j.stopped {
+		j.mu.Unlock()
+		return
+	}
+	if j.running > 0 {
+		switch j.options.overlap {
+		case OverlapSkip:
+			j.record(&Run{Scheduled: scheduled, Skipped: true})
+			j.mu.Unlock()
+			return
+		case OverlapQueue:
+			j.queued = append(j.queued, scheduled)
+			j.mu.Unlock()
+			return
+		}
+	}
+	j.running++
+	j.mu.Unlock()
+	j.start(scheduled)
+}
+
+// start submits the task of a run which is already counted in running
+func (j *Job[T]) start(scheduled time.Time) {
+	run := &Run{Scheduled: scheduled, Started: j.clock.Now()}
+	f := j.submitter.Submit(j.builder.BuildTask(j.params(scheduled)))
+	j.mu.Lock()
+	run.Future = f
+	j.record(run)
+	j.mu.Unlock()
+	go j.wait(run)
+}
+
+func (j *Job[T]) wait(run *Run) {
+	run.Future.Wait()
+	j.mu.Lock()
+	run.Finished = j.clock.Now()
+	run.Err = run.Future.Error()
+	j.running--
+	var next time.Time
+	if len(j.queued) > 0 {
+		next, j.queued = j.queued[0], j.queued[1:]
+		j.running++
+	}
+	j.mu.Unlock()
+	if !next.IsZero() {
+		j.start(next)
+	}
+}
+
+// record appends run to the history, j.mu must be held
+func (j *Job[T]) record(run *Run) {
+	if len(j.history) == j.options.history {
+		copy(j.history, j.history[1:])
+		j.history = j.history[:len(j.history)-1]
+	}
+	j.history = append(j.history, run)
+}
+
+// Scheduler submits tasks to an executor on cron schedules or fixed intervals
+type Scheduler[T any] struct {
+	submitter Submitter[T]
+	options   *options
+	jobs      map[string]*Job[T]
+	stopped   bool
+	mu        sync.Mutex
+}
+
+// Add starts a job named name which, on each activation of schedule, builds a task from builder with the params
+// returned by params for the activation time and submits it
+func (s *Scheduler[T]) Add(
+	name string,
+	schedule Schedule,
+	builder *conrate.TaskBuilder[T],
+	params func(scheduled time.Time) []T,
+	opts ...JobOption,
+) (*Job[T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return nil, ErrSchedulerStopped
+	}
+	if _, ok := s.jobs[name]; ok {
+		return nil, ErrDuplicateJob
+	}
+	j := &Job[T]{
+		name:      name,
+		schedule:  schedule,
+		builder:   builder,
+		params:    params,
+		submitter: s.submitter,
+		clock:     s.options.clock,
+		options:   newJobOptions(opts...),
+		stop:      make(chan struct{}),
+	}
+	s.jobs[name] = j
+	go j.loop()
+	return j, nil
+}
+
+// AddFunc is Add with a cron expression, see Parse
+func (s *Scheduler[T]) AddFunc(
+	name, expr string,
+	builder *conrate.TaskBuilder[T],
+	params func(scheduled time.Time) []T,
+	opts ...JobOption,
+) (*Job[T], error) {
+	schedule, err := Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return s.Add(name, schedule, builder, params, opts...)
+}
+
+func (s *Scheduler[T]) Job(name string) *Job[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[name]
+}
+
+// Remove stops and forgets the job named name, false if there is none
+func (s *Scheduler[T]) Remove(name string) bool {
+	s.mu.Lock()
+	j, ok := s.jobs[name]
+	delete(s.jobs, name)
+	s.mu.Unlock()
+	if ok {
+		j.Stop()
+	}
+	return ok
+}
+
+// Stop stops every job, the executor is left running
+func (s *Scheduler[T]) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	jobs := s.jobs
+	s.jobs = make(map[string]*Job[T])
+	s.mu.Unlock()
+	for _, j := range jobs {
+		j.Stop()
+	}
+}
+
+func New[T any](submitter Submitter[T], opts ...Option) *Scheduler[T] {
+	return &Scheduler[T]{
+		submitter: submitter,
+		options:   newOptions(opts...),
+		jobs:      make(map[string]*Job[T]),
+	}
+}
@@ -0,0 +1,270 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/riete/conrate"
+)
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// fakeClock only moves on Advance
+type fakeClock struct {
+	now     time.Time
+	waiters []fakeWaiter
+	mu      sync.Mutex
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = waiters
+}
+
+// awaitWaiters waits until n goroutines are blocked in After.
+func (c *fakeClock) awaitWaiters(t *testing.T, n int) {
+	t.Helper()
+	waitUntil(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.waiters) == n
+	})
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func constParams(scheduled time.Time) []time.Time {
+	return []time.Time{scheduled}
+}
+
+// TestSchedulerEvery expects:
+//   - a task submitted on every tick with the params built for the activation time;
+//   - each run recorded in History with its Future outcome.
+func TestSchedulerEvery(t *testing.T) {
+	executor := conrate.NewConcurrentExecutor[time.Time](4)
+	defer executor.Stop()
+	clock := newFakeClock()
+	s := New[time.Time](executor, WithClock(clock))
+	defer s.Stop()
+
+	got := make(chan time.Time, 3)
+	builder := conrate.NewTaskBuilder[time.Time]().WithTaskFunc(func(_ context.Context, scheduled time.Time) {
+		got <- scheduled
+	})
+	j, err := s.Add("every", Every(time.Minute), builder, constParams)
+	if err != nil {
+		t.Fatalf("Add() = %v, want nil", err)
+	}
+	start := clock.Now()
+	for i := 1; i <= 3; i++ {
+		clock.awaitWaiters(t, 1)
+		clock.Advance(time.Minute)
+		if scheduled := <-got; !scheduled.Equal(start.Add(time.Duration(i) * time.Minute)) {
+			t.Fatalf("tick %d param = %v, want %v", i, scheduled, start.Add(time.Duration(i)*time.Minute))
+		}
+		waitUntil(t, func() bool { return j.Running() == 0 })
+	}
+
+	history := j.History()
+	if len(history) != 3 {
+		t.Fatalf("len(History()) = %d, want 3", len(history))
+	}
+	for _, run := range history {
+		if run.Skipped || run.Err != nil || run.Future == nil || run.Finished.IsZero() {
+			t.Fatalf("run = %+v, want finished without error", run)
+		}
+	}
+}
+
+// blockingJob adds a one minute job whose runs block until release is closed.
+func blockingJob(t *testing.T, s *Scheduler[time.Time], release chan struct{}, opts ...JobOption) *Job[time.Time] {
+	builder := conrate.NewTaskBuilder[time.Time]().WithTaskFunc(func(context.Context, time.Time) {
+		<-release
+	})
+	j, err := s.Add("blocking", Every(time.Minute), builder, constParams, opts...)
+	if err != nil {
+		t.Fatalf("Add() = %v, want nil", err)
+	}
+	return j
+}
+
+// tick advances clock to the next activation of a one minute job.
+func tick(t *testing.T, clock *fakeClock) {
+	clock.awaitWaiters(t, 1)
+	clock.Advance(time.Minute)
+}
+
+// TestOverlap expects, while a run is still going:
+//   - OverlapSkip to record later activations as skipped;
+//   - OverlapQueue to start them one after another once it finishes;
+//   - OverlapAllow to start them right away.
+func TestOverlap(t *testing.T) {
+	for _, c := range []struct {
+		overlap     Overlap
+		running     int
+		wantRuns    int
+		wantSkipped int
+	}{
+		{OverlapSkip, 1, 3, 2},
+		{OverlapQueue, 1, 3, 0},
+		{OverlapAllow, 3, 3, 0},
+	} {
+		executor := conrate.NewConcurrentExecutor[time.Time](4)
+		clock := newFakeClock()
+		s := New[time.Time](executor, WithClock(clock))
+		release := make(chan struct{})
+		j := blockingJob(t, s, release, WithOverlap(c.overlap))
+
+		for range 3 {
+			tick(t, clock)
+		}
+		clock.awaitWaiters(t, 1)
+		waitUntil(t, func() bool { return j.Running() == c.running })
+		close(release)
+		waitUntil(t, func() bool { return j.Running() == 0 && len(j.History()) == c.wantRuns })
+
+		var skipped int
+		for _, run := range j.History() {
+			if run.Skipped {
+				skipped++
+			} else if run.Err != nil || run.Finished.IsZero() {
+				t.Fatalf("overlap %d: run = %+v, want finished without error", c.overlap, run)
+			}
+		}
+		if skipped != c.wantSkipped {
+			t.Fatalf("overlap %d: %d skipped runs, want %d", c.overlap, skipped, c.wantSkipped)
+		}
+		s.Stop()
+		executor.Stop()
+	}
+}
+
+// TestJitter expects a run to start within jitter after its activation time.
+func TestJitter(t *testing.T) {
+	executor := conrate.NewConcurrentExecutor[time.Time](4)
+	defer executor.Stop()
+	clock := newFakeClock()
+	s := New[time.Time](executor, WithClock(clock))
+	defer s.Stop()
+
+	builder := conrate.NewTaskBuilder[time.Time]().WithTaskFunc(func(context.Context, time.Time) {})
+	j, _ := s.Add("jitter", Every(time.Minute), builder, constParams, WithJitter(10*time.Second))
+	start := clock.Now()
+	clock.awaitWaiters(t, 1)
+	clock.Advance(time.Minute + 10*time.Second)
+	waitUntil(t, func() bool { return len(j.History()) == 1 })
+
+	run := j.History()[0]
+	if !run.Scheduled.Equal(start.Add(time.Minute)) {
+		t.Fatalf("Scheduled = %v, want %v", run.Scheduled, start.Add(time.Minute))
+	}
+	if run.Started.Before(run.Scheduled) || run.Started.After(run.Scheduled.Add(10*time.Second)) {
+		t.Fatalf("Started = %v, want within 10s after %v", run.Started, run.Scheduled)
+	}
+}
+
+// TestHistorySize expects History to keep the last WithHistory runs only.
+func TestHistorySize(t *testing.T) {
+	executor := conrate.NewConcurrentExecutor[time.Time](4)
+	defer executor.Stop()
+	clock := newFakeClock()
+	s := New[time.Time](executor, WithClock(clock))
+	defer s.Stop()
+
+	builder := conrate.NewTaskBuilder[time.Time]().WithTaskFunc(func(context.Context, time.Time) {})
+	j, _ := s.Add("history", Every(time.Minute), builder, constParams, WithHistory(2), WithOverlap(OverlapAllow))
+	for range 3 {
+		tick(t, clock)
+	}
+	waitUntil(t, func() bool {
+		history := j.History()
+		return len(history) == 2 && history[1].Scheduled.Equal(clock.Now()) && j.Running() == 0
+	})
+	if got := j.History()[0].Scheduled; !got.Equal(clock.Now().Add(-time.Minute)) {
+		t.Fatalf("oldest Scheduled = %v, want %v", got, clock.Now().Add(-time.Minute))
+	}
+}
+
+// TestSchedulerStop expects:
+//   - Add of a duplicate name to fail with ErrDuplicateJob;
+//   - Remove and Stop to stop ticking;
+//   - Add after Stop to fail with ErrSchedulerStopped.
+func TestSchedulerStop(t *testing.T) {
+	executor := conrate.NewConcurrentExecutor[time.Time](4)
+	defer executor.Stop()
+	clock := newFakeClock()
+	s := New[time.Time](executor, WithClock(clock))
+
+	builder := conrate.NewTaskBuilder[time.Time]().WithTaskFunc(func(context.Context, time.Time) {})
+	j, err := s.AddFunc("a", "@every 1m", builder, constParams)
+	if err != nil {
+		t.Fatalf("AddFunc() = %v, want nil", err)
+	}
+	if _, err := s.AddFunc("a", "@hourly", builder, constParams); !errors.Is(err, ErrDuplicateJob) {
+		t.Fatalf("AddFunc() = %v, want ErrDuplicateJob", err)
+	}
+	if _, err := s.AddFunc("b", "bad", builder, constParams); !errors.Is(err, ErrInvalidSchedule) {
+		t.Fatalf("AddFunc() = %v, want ErrInvalidSchedule", err)
+	}
+	clock.awaitWaiters(t, 1)
+	if !s.Remove("a") || s.Job("a") != nil {
+		t.Fatal("expected job a removed")
+	}
+	clock.Advance(time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	if got := len(j.History()); got != 0 {
+		t.Fatalf("len(History()) = %d after Remove(), want 0", got)
+	}
+
+	s.Stop()
+	if _, err := s.Add("c", Every(time.Minute), builder, constParams); !errors.Is(err, ErrSchedulerStopped) {
+		t.Fatalf("Add() = %v, want ErrSchedulerStopped", err)
+	}
+}
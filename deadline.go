@@ -0,0 +1,33 @@
+package conrate
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrTaskDeadline = errors.New("task deadline exceeded")
+
+// checkDeadline flags the future of task as at risk once its pending params cannot start before the deadline
+// even if the task got every executor token, cost is the cost of the run of n params about to start
+func (e *Executor[T]) checkDeadline(task *Task[T], cost, n int) {
+	if task.deadline.IsZero() || task.future.atRisk.Load() {
+		return
+	}
+	rate := task.lc.limiter.Capacity()
+	if qps, _ := e.limits(task); qps > 0 {
+		rate = min(rate, qps)
+	}
+	if rate <= 0 {
+		return
+	}
+	runs := float64(task.counter.Pending()) / float64(n)
+	tokens := runs * float64(cost)
+	if e.mode == ConcurrencyMode {
+		tokens = runs
+	}
+	// the limiter burst is available at once
+	wait := max(tokens-float64(rate), 0) / float64(rate)
+	if time.Now().Add(time.Duration(wait * float64(time.Second))).After(task.deadline) {
+		task.future.atRisk.Store(true)
+	}
+}
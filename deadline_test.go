@@ -0,0 +1,135 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestEDF expects SchedulingEDF to give tokens to the task with a deadline before the task without one: at most a
+// couple of params of the task without deadline start before the last param of the task with a deadline.
+func TestEDF(t *testing.T) {
+	p := NewRateLimitExecutor[int](10, WithSchedulingPolicy(SchedulingEDF))
+	defer p.Stop()
+
+	var mu sync.Mutex
+	var order []string
+	task := func(name string) func(context.Context, int) {
+		return func(context.Context, int) {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+		}
+	}
+	p.Pause()
+	f := p.Submit(
+		NewTaskBuilder[int]().WithTaskFunc(task("none")).BuildTask(ints(10)),
+		NewTaskBuilder[int]().WithTaskFunc(task("late")).WithDeadline(time.Now().Add(time.Minute)).BuildTask(ints(10)),
+		NewTaskBuilder[int]().WithTaskFunc(task("soon")).WithDeadline(time.Now().Add(30*time.Second)).BuildTask(ints(10)),
+	)
+	time.Sleep(100 * time.Millisecond)
+	p.Resume()
+	if err := f.WaitTimeout(10 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	before := func(name, last string) int {
+		n := 0
+		for i := len(order) - 1; i >= 0; i-- {
+			if order[i] == last {
+				for _, o := range order[:i] {
+					if o == name {
+						n++
+					}
+				}
+				break
+			}
+		}
+		return n
+	}
+	if got := before("none", "soon"); got > 2 {
+		t.Fatalf("%d params without deadline started before the last of the earliest deadline: %v", got, order)
+	}
+	if got := before("late", "soon"); got > 2 {
+		t.Fatalf("%d params of the later deadline started before the last of the earliest deadline: %v", got, order)
+	}
+	if got := before("none", "late"); got > 2 {
+		t.Fatalf("%d params without deadline started before the last with a deadline: %v", got, order)
+	}
+}
+
+// TestDeadlineExpired expects, for 20 params at 5 qps with a 300ms deadline:
+//   - Future.AtRisk to turn true before the deadline;
+//   - params not started by the deadline to be canceled with ErrTaskDeadline and counted as Expired.
+func TestDeadlineExpired(t *testing.T) {
+	p := NewRateLimitExecutor[int](5)
+	defer p.Stop()
+
+	f := p.Submit(NewTaskBuilder[int]().
+		WithTaskFunc(func(context.Context, int) {}).
+		WithDeadline(time.Now().Add(300 * time.Millisecond)).
+		BuildTask(ints(20)))
+	waitUntil(t, 200*time.Millisecond, f.AtRisk)
+	if err := f.WaitTimeout(3 * time.Second); !errors.Is(err, ErrTaskDeadline) {
+		t.Fatalf("WaitTimeout() = %v, want ErrTaskDeadline", err)
+	}
+
+	c := f.Counter()
+	if c.Expired() == 0 || c.Expired() != c.Canceled() {
+		t.Fatalf("Expired() = %d, Canceled() = %d, want equal and > 0", c.Expired(), c.Canceled())
+	}
+	if got := c.Expired() + c.Completed(); got != 20 {
+		t.Fatalf("expired + completed = %d, want 20", got)
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
+
+// TestDeadlineRunning expects a param started before the deadline to run to completion with a context the deadline
+// does not cancel.
+func TestDeadlineRunning(t *testing.T) {
+	p := NewRateLimitExecutor[int](100)
+	defer p.Stop()
+
+	var canceled atomic.Bool
+	f := p.Submit(NewTaskBuilder[int]().
+		WithTaskFunc(func(ctx context.Context, _ int) {
+			select {
+			case <-ctx.Done():
+				canceled.Store(true)
+			case <-time.After(300 * time.Millisecond):
+			}
+		}).
+		WithDeadline(time.Now().Add(100 * time.Millisecond)).
+		BuildTask(ints(1)))
+	if err := f.WaitTimeout(3 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	if canceled.Load() {
+		t.Fatal("expected the running param not to be canceled by the deadline")
+	}
+	if got := f.Counter().Completed(); got != 1 {
+		t.Fatalf("Completed() = %d, want 1", got)
+	}
+}
+
+// TestDeadlineInTime expects a task which can finish before its deadline not to be at risk.
+func TestDeadlineInTime(t *testing.T) {
+	p := NewRateLimitExecutor[int](100)
+	defer p.Stop()
+
+	f := p.Submit(NewTaskBuilder[int]().
+		WithTaskFunc(func(context.Context, int) {}).
+		WithDeadline(time.Now().Add(5 * time.Second)).
+		BuildTask(ints(20)))
+	if err := f.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	if f.AtRisk() {
+		t.Fatal("expected task not at risk")
+	}
+}
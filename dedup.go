@@ -0,0 +1,78 @@
+package conrate
+
+import (
+	"sync"
+	"time"
+)
+
+// flight is the execution of a param shared by the params with the same dedup key
+type flight struct {
+	key  string
+	done chan struct{}
+	err  error
+	// abandoned is true if the leader stopped before its param ran to an outcome
+	abandoned bool
+}
+
+// dedup tracks the flights of an executor and caches the keys of succeeded flights for ttl
+type dedup struct {
+	flights map[string]*flight
+	cache   map[string]time.Time
+	ttl     time.Duration
+	swept   time.Time
+	mu      sync.Mutex
+}
+
+// join returns the flight of key and true if the caller leads it, a cached key returns a done flight
+func (d *dedup) join(key string) (*flight, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if expires, ok := d.cache[key]; ok {
+		if time.Now().Before(expires) {
+			f := &flight{key: key, done: make(chan struct{})}
+			close(f.done)
+			return f, false
+		}
+		delete(d.cache, key)
+	}
+	if f, ok := d.flights[key]; ok {
+		return f, false
+	}
+	f := &flight{key: key, done: make(chan struct{})}
+	d.flights[key] = f
+	return f, true
+}
+
+// finish resolves a led flight with the outcome of its param
+func (d *dedup) finish(f *flight, err error) {
+	d.mu.Lock()
+	delete(d.flights, f.key)
+	if err == nil && d.ttl > 0 {
+		now := time.Now()
+		d.cache[f.key] = now.Add(d.ttl)
+		if now.Sub(d.swept) > d.ttl {
+			d.swept = now
+			for key, expires := range d.cache {
+				if now.After(expires) {
+					delete(d.cache, key)
+				}
+			}
+		}
+	}
+	d.mu.Unlock()
+	f.err = err
+	close(f.done)
+}
+
+// abandon resolves a led flight whose param did not run to an outcome, a follower takes over instead of sharing it
+func (d *dedup) abandon(f *flight) {
+	d.mu.Lock()
+	delete(d.flights, f.key)
+	d.mu.Unlock()
+	f.abandoned = true
+	close(f.done)
+}
+
+func newDedup(ttl time.Duration) *dedup {
+	return &dedup{flights: make(map[string]*flight), cache: make(map[string]time.Time), ttl: ttl}
+}
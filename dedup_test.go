@@ -0,0 +1,138 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func identity(s string) string {
+	return s
+}
+
+// inFlight tells if key has a flight in p.
+func inFlight(p *Executor[string], key string) bool {
+	p.dedup.mu.Lock()
+	defer p.dedup.mu.Unlock()
+	_, ok := p.dedup.flights[key]
+	return ok
+}
+
+// TestDedupWithinTask expects duplicate params of a task to run once and still count as completed.
+func TestDedupWithinTask(t *testing.T) {
+	p := NewRateLimitExecutor[string](100)
+	defer p.Stop()
+
+	var calls atomic.Int64
+	f := p.Submit(NewTaskBuilder[string]().
+		WithDedupKey(identity).
+		WithTaskFunc(func(context.Context, string) {
+			calls.Add(1)
+			time.Sleep(100 * time.Millisecond)
+		}).
+		BuildTask([]string{"a", "a", "b", "a", "b"}))
+	if err := f.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("task function called %d times, want 2", got)
+	}
+	if got := f.Counter().Completed(); got != 5 {
+		t.Fatalf("Completed() = %d, want 5", got)
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
+
+// TestDedupAcrossTasks expects a duplicate param of another task to share the outcome of the running param.
+func TestDedupAcrossTasks(t *testing.T) {
+	p := NewConcurrentExecutor[string](4)
+	defer p.Stop()
+
+	var calls atomic.Int64
+	release := make(chan struct{})
+	errFailed := errors.New("failed")
+	builder := NewTaskBuilder[string]().
+		WithDedupKey(identity).
+		WithTaskFuncE(func(context.Context, string) error {
+			calls.Add(1)
+			<-release
+			return errFailed
+		})
+	first := p.Submit(builder.BuildTask([]string{"a"}))
+	waitUntil(t, 3*time.Second, func() bool { return p.Counter().Running() == 1 })
+	second := p.Submit(builder.BuildTask([]string{"a"}))
+	// the second task joins the flight as soon as it is dispatched
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	for _, f := range []*Future{first, second} {
+		if err := f.WaitTimeout(5 * time.Second); !errors.Is(err, errFailed) {
+			t.Fatalf("WaitTimeout() = %v, want errFailed", err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("task function called %d times, want 1", got)
+	}
+	if got := p.Counter().Failed(); got != 2 {
+		t.Fatalf("Failed() = %d, want 2", got)
+	}
+}
+
+// TestDedupCache expects WithDedupCache to complete a param whose key succeeded within ttl without running it,
+// failed keys are not cached.
+func TestDedupCache(t *testing.T) {
+	p := NewRateLimitExecutor[string](100, WithDedupCache(time.Hour))
+	defer p.Stop()
+
+	var calls atomic.Int64
+	builder := NewTaskBuilder[string]().
+		WithDedupKey(identity).
+		WithTaskFuncE(func(_ context.Context, s string) error {
+			calls.Add(1)
+			if s == "bad" {
+				return errors.New("bad")
+			}
+			return nil
+		})
+	for range 3 {
+		p.Wait(p.Submit(builder.BuildTask([]string{"good", "bad"})))
+	}
+	if got := calls.Load(); got != 4 {
+		t.Fatalf("task function called %d times, want 4", got)
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
+
+// TestDedupAbandoned expects a duplicate of a param whose task is canceled before it runs to take over and run
+// instead of failing with the cancellation of an unrelated task.
+func TestDedupAbandoned(t *testing.T) {
+	p := NewConcurrentExecutor[string](1)
+	defer p.Stop()
+
+	release := make(chan struct{})
+	var ran sync.Map
+	builder := NewTaskBuilder[string]().WithDedupKey(identity).WithTaskFunc(func(_ context.Context, s string) {
+		ran.Store(s, true)
+		<-release
+	})
+	leader := p.Submit(builder.BuildTask([]string{"x", "y"}))
+	waitUntil(t, 3*time.Second, func() bool { return inFlight(p, "y") })
+	follower := p.Submit(builder.BuildTask([]string{"y"}))
+	time.Sleep(50 * time.Millisecond)
+
+	leader.Cancel()
+	close(release)
+	if err := follower.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("follower WaitTimeout() = %v, want nil", err)
+	}
+	if _, ok := ran.Load("y"); !ok {
+		t.Fatal("expected y to run for the follower")
+	}
+	if got := follower.Counter().Completed(); got != 1 {
+		t.Fatalf("Completed() = %d, want 1", got)
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
@@ -0,0 +1,171 @@
+package conrate
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// delayed is a task waiting in a delayQueue until at
+type delayed[T any] struct {
+	at         time.Time
+	task       *Task[T]
+	index      int
+	stopCancel func() bool
+}
+
+type delayHeap[T any] []*delayed[T]
+
+func (h delayHeap[T]) Len() int           { return len(h) }
+func (h delayHeap[T]) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h delayHeap[T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *delayHeap[T]) Push(x any) {
+	item := x.(*delayed[T])
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *delayHeap[T]) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*h = old[:n-1]
+	return item
+}
+
+// delayQueue is a timer heap of tasks submitted by SubmitAt or SubmitAfter, due hands a task over to the submit queue
+// once it is due, reject fails a task canceled before it is due or still waiting when the queue is closed
+type delayQueue[T any] struct {
+	items  delayHeap[T]
+	wake   chan struct{}
+	stop   chan struct{}
+	closed bool
+	due    func(*Task[T])
+	reject func(*Task[T], error)
+	mu     sync.Mutex
+}
+
+func (q *delayQueue[T]) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// add schedules task at at, it is rejected with ErrExecutorStopped if the queue is closed
+func (q *delayQueue[T]) add(at time.Time, task *Task[T]) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		go q.reject(task, ErrExecutorStopped)
+		return
+	}
+	item := &delayed[T]{at: at, task: task}
+	item.stopCancel = context.AfterFunc(task.ctx, func() {
+		if q.remove(item) {
+			q.reject(task, context.Cause(task.ctx))
+		}
+	})
+	heap.Push(&q.items, item)
+	if item.index == 0 {
+		q.notify()
+	}
+}
+
+func (q *delayQueue[T]) remove(item *delayed[T]) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if item.index < 0 || q.closed {
+		return false
+	}
+	heap.Remove(&q.items, item.index)
+	return true
+}
+
+func (q *delayQueue[T]) run() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		q.mu.Lock()
+		var wait time.Duration = -1
+		for len(q.items) > 0 {
+			next := q.items[0]
+			if wait = time.Until(next.at); wait > 0 {
+				break
+			}
+			heap.Pop(&q.items)
+			next.stopCancel()
+			q.due(next.task)
+		}
+		q.mu.Unlock()
+		if wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-q.stop:
+				return
+			case <-q.wake:
+			case <-timer.C:
+			}
+			continue
+		}
+		select {
+		case <-q.stop:
+			return
+		case <-q.wake:
+		}
+	}
+}
+
+// close stops the timer and rejects the tasks which are not due with ErrExecutorStopped
+func (q *delayQueue[T]) close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.stop)
+	items := q.items
+	q.items = nil
+	q.mu.Unlock()
+	for _, item := range items {
+		item.stopCancel()
+		q.reject(item.task, ErrExecutorStopped)
+	}
+}
+
+func newDelayQueue[T any](due func(*Task[T]), reject func(*Task[T], error)) *delayQueue[T] {
+	return &delayQueue[T]{
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		due:    due,
+		reject: reject,
+	}
+}
+
+// SubmitAt keeps tasks pending until at and then submits them as Submit does, tasks count in Counter.Pending from
+// now on and can be canceled through the Future before they are due
+func (e *Executor[T]) SubmitAt(at time.Time, tasks ...*Task[T]) *Future {
+	delayed := e.current().delayed
+	return e.accept(tasks, func(task *Task[T]) {
+		delayed.add(at, task)
+	})
+}
+
+// SubmitAfter is SubmitAt(time.Now().Add(d), tasks...)
+func (e *Executor[T]) SubmitAfter(d time.Duration, tasks ...*Task[T]) *Future {
+	return e.SubmitAt(time.Now().Add(d), tasks...)
+}
+
+// due hands a due task over to the submit queue without blocking the timer
+func (e *Executor[T]) due(task *Task[T]) {
+	go e.enqueue(context.Background(), task, true)
+}
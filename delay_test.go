@@ -0,0 +1,88 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSubmitAfter expects:
+//   - params to count as pending from SubmitAfter on and not to run before they are due;
+//   - tasks to run once due, the earliest first.
+func TestSubmitAfter(t *testing.T) {
+	p := NewConcurrentExecutor[int](4)
+	defer p.Stop()
+
+	order := make(chan string, 2)
+	builder := func(name string) *TaskBuilder[int] {
+		return NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) { order <- name })
+	}
+	start := time.Now()
+	late := p.SubmitAfter(150*time.Millisecond, builder("late").BuildTask(ints(1)))
+	early := p.SubmitAt(start.Add(50*time.Millisecond), builder("early").BuildTask(ints(1)))
+	if got := p.Counter().Pending(); got != 2 {
+		t.Fatalf("Pending() = %d, want 2", got)
+	}
+	if err := All(early, late).WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("tasks done after %v, want >= 150ms", elapsed)
+	}
+	if first := <-order; first != "early" {
+		t.Fatalf("first task = %s, want early", first)
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
+
+// TestSubmitAfterCancel expects Future.Cancel before the task is due to cancel all its params without running them.
+func TestSubmitAfterCancel(t *testing.T) {
+	p := NewConcurrentExecutor[int](4)
+	defer p.Stop()
+
+	ran := make(chan struct{}, 3)
+	f := p.SubmitAfter(time.Hour, NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		ran <- struct{}{}
+	}).BuildTask(ints(3)))
+	f.Cancel()
+	if err := f.WaitTimeout(3 * time.Second); !errors.Is(err, context.Canceled) {
+		t.Fatalf("WaitTimeout() = %v, want context.Canceled", err)
+	}
+	assertCounterZeroPending(t, p.Counter())
+	if got := p.Counter().Canceled(); got != 3 {
+		t.Fatalf("Canceled() = %d, want 3", got)
+	}
+	if len(ran) != 0 {
+		t.Fatal("expected canceled task not to run")
+	}
+}
+
+// TestSubmitAfterBeforeDue expects:
+//   - tasks which are not due to fail with ErrExecutorStopped on Stop and Shutdown;
+//   - SubmitAfter on a stopped executor to fail with ErrExecutorStopped.
+func TestSubmitAfterBeforeDue(t *testing.T) {
+	p := NewConcurrentExecutor[int](4)
+	f := p.SubmitAfter(time.Hour, queuedTask(2))
+	p.Stop()
+	if err := f.WaitTimeout(3 * time.Second); !errors.Is(err, ErrExecutorStopped) {
+		t.Fatalf("WaitTimeout() after Stop() = %v, want ErrExecutorStopped", err)
+	}
+	assertCounterZeroPending(t, p.Counter())
+	if err := p.SubmitAfter(time.Millisecond, queuedTask(1)).Error(); !errors.Is(err, ErrExecutorStopped) {
+		t.Fatalf("SubmitAfter() after Stop() = %v, want ErrExecutorStopped", err)
+	}
+
+	p = NewConcurrentExecutor[int](4)
+	f = p.SubmitAfter(time.Hour, queuedTask(2))
+	report, err := p.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("Shutdown() = %v, want nil", err)
+	}
+	if report.Canceled != 2 {
+		t.Fatalf("report = %+v, want 2 canceled", report)
+	}
+	if err := f.WaitTimeout(3 * time.Second); !errors.Is(err, ErrExecutorStopped) {
+		t.Fatalf("WaitTimeout() after Shutdown() = %v, want ErrExecutorStopped", err)
+	}
+}
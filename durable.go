@@ -0,0 +1,94 @@
+package conrate
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (e *Executor[T]) newTaskID() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(e.seq.Add(1), 36)
+}
+
+// persist saves the record of a submitted task, a resumed task keeps its record id
+func (e *Executor[T]) persist(task *Task[T]) error {
+	if e.store == nil || task.source != nil {
+		return nil
+	}
+	if task.id == "" {
+		task.id = e.newTaskID()
+	}
+	record := TaskRecord{ID: task.id, Name: task.name, Params: make(map[int][]byte, len(task.param))}
+	for i, param := range task.param {
+		data, err := e.codec.Encode(param)
+		if err != nil {
+			return err
+		}
+		record.Params[task.index(i)] = data
+	}
+	return e.store.Save(record)
+}
+
+// checkpoint marks param i of task processed
+func (e *Executor[T]) checkpoint(task *Task[T], i int) {
+	if e.store == nil || task.id == "" {
+		return
+	}
+	if err := e.store.MarkDone(task.id, task.index(i)); err != nil {
+		task.failStore(err)
+	}
+}
+
+// settle deletes the record of a finished task, tasks stopped by the executor are kept for Executor.ResumeFrom
+func (e *Executor[T]) settle(task *Task[T]) {
+	if e.store == nil || task.id == "" || errors.Is(task.err, ErrExecutorStopped) {
+		return
+	}
+	if err := e.store.Delete(task.id); err != nil {
+		task.failStore(err)
+	}
+}
+
+// ResumeFrom re-submits the params of tasks recorded in store which were not processed, builder returns the
+// TaskBuilder of a task name given by TaskBuilder.WithName, resumed tasks keep their record id in the executor Store
+func (e *Executor[T]) ResumeFrom(store Store, builder func(name string) *TaskBuilder[T]) (*Future, error) {
+	records, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(records, func(a, b TaskRecord) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	tasks := make([]*Task[T], 0, len(records))
+	for _, record := range records {
+		if len(record.Params) == 0 {
+			if err = store.Delete(record.ID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		b := builder(record.Name)
+		if b == nil {
+			return nil, fmt.Errorf("no task builder for task %q named %q", record.ID, record.Name)
+		}
+		indexes := slices.Sorted(maps.Keys(record.Params))
+		params := make([]T, 0, len(indexes))
+		for _, index := range indexes {
+			param, err := e.codec.Decode(record.Params[index])
+			if err != nil {
+				return nil, fmt.Errorf("decode param %d of task %q: %w", index, record.ID, err)
+			}
+			params = append(params, param)
+		}
+		task := b.BuildTask(params)
+		task.id = record.ID
+		task.name = record.Name
+		task.indexes = indexes
+		tasks = append(tasks, task)
+	}
+	return e.Submit(tasks...), nil
+}
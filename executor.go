@@ -4,12 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"iter"
 	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/riete/robinx"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -20,278 +20,465 @@ type ExecutorMode int64
 const ConcurrencyMode ExecutorMode = 0
 const RateLimitMode ExecutorMode = 1
 
-type Future struct {
-	wg          *sync.WaitGroup
-	cancelFuncs []context.CancelFunc
-	err         error
-}
-
-func (f *Future) Wait() {
-	if f.wg != nil {
-		f.wg.Wait()
-	}
-}
-
-func (f *Future) Cancel() {
-	for _, cancel := range f.cancelFuncs {
-		cancel()
-	}
-}
-
-func (f *Future) Error() error {
-	return f.err
-}
-
-type Counter struct {
-	running   *atomic.Int64
-	pending   *atomic.Int64
-	completed *atomic.Int64
-	canceled  *atomic.Int64
-}
-
-func (c *Counter) Running() int64 {
-	return c.running.Load()
-}
-
-func (c *Counter) Pending() int64 {
-	return c.pending.Load()
-}
-
-func (c *Counter) Completed() int64 {
-	return c.completed.Load()
-}
-
-func (c *Counter) Canceled() int64 {
-	return c.canceled.Load()
-}
-
-func (c *Counter) Reset() {
-	c.running.Store(0)
-	c.pending.Store(0)
-	c.completed.Store(0)
-	c.canceled.Store(0)
-}
-
 type Executor[T any] struct {
-	mode        ExecutorMode
-	limiter     *RateLimiter
-	task        chan *Task[T]
-	idle        *semaphore.Weighted
-	counter     *Counter
-	stopped     bool
-	stop        chan struct{}
-	runningTask *atomic.Int64
-	picker      robinx.Picker[*Task[T]]
-	mu          sync.Mutex
-}
-
-func (e *Executor[T]) schedule() {
+	mode      ExecutorMode
+	lc        *lifecycle[T]
+	state     State
+	options   *options
+	idle      *semaphore.Weighted
+	counter   *Counter
+	active    map[*Task[T]]struct{}
+	idleTask  chan struct{}
+	activeMu  sync.Mutex
+	scheduler Scheduler[T]
+	// scheduled is the number of tasks added to scheduler
+	scheduled atomic.Int64
+	store     Store
+	codec     Codec[T]
+	dedup     *dedup
+	breakers  *breakers
+	tenants   map[string]*Counter
+	tenantsMu sync.Mutex
+	seq       atomic.Uint64
+	mu        sync.Mutex
+}
+
+// schedule takes executor tokens from the limiter one at a time and hands each to a ready task, a token no task can
+// take is held until a task is dispatched or takes a token instead of being dropped
+func (e *Executor[T]) schedule(lc *lifecycle[T]) {
 	for {
-		select {
-		case <-e.stop:
+		if !e.token(lc) {
 			return
-		case <-e.limiter.wait:
-			item := e.picker.Next()
-			if item == nil {
-				continue
+		}
+		for {
+			if task := e.ready(); task != nil {
+				// only schedule sends to task.wait, a ready task has room
+				task.wait <- struct{}{}
+				break
 			}
 			select {
-			case <-e.stop:
+			case <-lc.stop:
 				return
-			case item.Item().wait <- struct{}{}:
-			default:
+			case <-lc.demand:
+			}
+			if lc.limiter.holding() {
+				// a token held across a Pause or a Backoff is dropped
+				break
 			}
 		}
 	}
 }
 
-func (e *Executor[T]) start() {
-	go e.schedule()
-	for task := range e.task {
+// ready asks the scheduler for the next ready task
+func (e *Executor[T]) ready() *Task[T] {
+	return nextReady(e.scheduler, int(e.scheduled.Load()))
+}
+
+func (e *Executor[T]) start(lc *lifecycle[T]) {
+	go e.schedule(lc)
+	go lc.delayed.run()
+	for {
+		task, ok := lc.queue.pop()
+		if !ok {
+			return
+		}
+		task.lc = lc
+		size := min(task.weight, lc.limiter.Capacity())
+		qps, inFlight := e.limits(task)
+		for _, limit := range []int{qps, inFlight} {
+			if limit > 0 {
+				size = min(size, limit)
+			}
+		}
+		task.wait = make(chan struct{}, size)
+		e.scheduler.Add(task)
+		e.scheduled.Add(1)
+		lc.signal()
 		go e.dispatch(task)
 	}
 }
 
+// dispatch runs the params of task, task is active from submit until dispatch returns
 func (e *Executor[T]) dispatch(task *Task[T]) {
-	e.runningTask.Add(1)
-	defer e.runningTask.Add(-1)
-	if task.maxConcurrency > 0 {
-		e.limitedRun(task)
-	} else {
-		e.unlimitedRun(task)
+	defer e.untrack(task)
+	canceled := new(atomic.Int64)
+	canceled.Store(int64(len(task.param)))
+	defer e.finish(task, canceled)
+	e.runParams(task, canceled)
+}
+
+// finish settles counters of params never run, hands the tokens task did not take over to other tasks and records
+// why the task ended early
+func (e *Executor[T]) finish(task *Task[T], canceled *atomic.Int64) {
+	e.scheduler.Remove(task)
+	e.scheduled.Add(-1)
+	// tokens buffered for task and not taken go to the other tasks
+	e.reclaim(task)
+	n := canceled.Load()
+	if n > 0 || (task.source != nil && !task.drained) {
+		task.counter.addCanceled(n)
+		task.counter.addPending(-n)
+		task.err = e.stopCause(task)
+		if errors.Is(task.err, ErrTaskDeadline) {
+			task.counter.addExpired(n)
+		}
+	}
+	e.settle(task)
+	task.done()
+	task.lc.queue.release()
+}
+
+// params yields the runs of task once the circuit breaker lets them through, a param whose dedup key is in flight
+// or cached shares its outcome instead of running and a run costing more than the capacity fails. A led param which
+// is not spawned resolves its flight with the reason it did not run, or abandons it for a follower to take over if
+// the task stopped.
+func (e *Executor[T]) params(task *Task[T], wg *sync.WaitGroup, canceled *atomic.Int64) iter.Seq2[int, []T] {
+	dedup := task.dedupKey != nil && task.batchFunc == nil
+	if !dedup && e.breakers == nil && task.costFunc == nil {
+		return task.params(canceled)
+	}
+	return func(yield func(int, []T) bool) {
+		// takeover receives the params whose flight was abandoned, followers counts the params waiting for a flight
+		// and settled wakes up the wait for followers
+		takeover := make(chan item[T])
+		settled := make(chan struct{}, 1)
+		exited := make(chan struct{})
+		defer close(exited)
+		var followers atomic.Int64
+		next := func(i int, params []T) bool {
+			if dedup {
+				f, lead := e.dedup.join(task.dedupKey(params[0]))
+				if !lead {
+					followers.Add(1)
+					wg.Go(func() {
+						<-f.done
+						if f.abandoned {
+							// the leader never ran, a follower takes over
+							select {
+							case takeover <- item[T]{index: i, params: params}:
+							case <-exited:
+							}
+							return
+						}
+						e.settleRun(task, i, params[:1], f.err, canceled)
+						followers.Add(-1)
+						select {
+						case settled <- struct{}{}:
+						default:
+						}
+					})
+					return true
+				}
+				task.lead(i, f)
+			}
+			b := e.breaker(task, params)
+			err := e.fits(task, params)
+			if err == nil && b != nil {
+				err = e.admit(task, b)
+			}
+			if err == nil {
+				if yield(i, params) {
+					return true
+				}
+				if b != nil {
+					b.cancel()
+				}
+				err = e.stopCause(task)
+			}
+			e.resolve(task, i, err)
+			switch {
+			case errors.Is(err, ErrCircuitOpen):
+				task.counter.addShortCircuited(int64(len(params)))
+			case !errors.Is(err, ErrCostExceedsCapacity):
+				return false
+			}
+			e.settleRun(task, i, params, err, canceled)
+			return true
+		}
+		// take runs a param taken over, false once the task stopped
+		take := func(it item[T]) bool {
+			followers.Add(-1)
+			return next(it.index, it.params)
+		}
+		for i, params := range task.params(canceled) {
+			for drained := false; !drained; {
+				select {
+				case it := <-takeover:
+					if !take(it) {
+						return
+					}
+				default:
+					drained = true
+				}
+			}
+			if !next(i, params) {
+				return
+			}
+		}
+		for followers.Load() > 0 {
+			select {
+			case it := <-takeover:
+				if !take(it) {
+					return
+				}
+			case <-settled:
+			}
+		}
 	}
 }
 
-func (e *Executor[T]) limitedRun(task *Task[T]) {
-	if e.mode == RateLimitMode {
-		e.limitedRateLimitRun(task)
-	} else {
-		e.limitedConcurrentRun(task)
+// item is a param of a task with its index
+type item[T any] struct {
+	index  int
+	params []T
+}
+
+// resolve ends the flight led by param index with err, a flight whose leader did not run to an outcome because its
+// task stopped is abandoned so that a follower takes over
+func (e *Executor[T]) resolve(task *Task[T], index int, err error) {
+	f := task.lead(index, nil)
+	if f == nil {
+		return
+	}
+	if err != nil && e.stopCause(task) != nil {
+		e.dedup.abandon(f)
+		return
+	}
+	e.dedup.finish(f, err)
+}
+
+// settleRun settles params of task which did not run with err, the outcome of the flight they joined,
+// ErrCircuitOpen or ErrCostExceedsCapacity
+func (e *Executor[T]) settleRun(task *Task[T], index int, params []T, err error, canceled *atomic.Int64) {
+	n := int64(len(params))
+	canceled.Add(-n)
+	task.counter.addPending(-n)
+	task.counter.addCompleted(n)
+	if err != nil {
+		task.failItems(n, err)
+	}
+	for i := range params {
+		e.checkpoint(task, index+i)
 	}
 }
 
-func (e *Executor[T]) unlimitedRun(task *Task[T]) {
-	if e.mode == RateLimitMode {
-		e.unlimitedRateLimitRun(task)
-	} else {
-		e.unlimitedConcurrentRun(task)
+// stopCause is why the params of task stopped being dispatched
+func (e *Executor[T]) stopCause(task *Task[T]) error {
+	select {
+	case <-task.lc.stop:
+		return ErrExecutorStopped
+	default:
+		return context.Cause(task.ctx)
 	}
 }
 
-func (e *Executor[T]) run(task *Task[T], param T, canceled *atomic.Int64) {
-	canceled.Add(-1)
-	e.counter.pending.Add(-1)
-	e.counter.running.Add(1)
+// run runs one param or one batch of params of a task built WithBatching, err is the failure of a single param or
+// of a batch, a *BatchError if some of its items failed, or the *RetryAfterError of params back to pending if they
+// may be requeued
+func (e *Executor[T]) run(task *Task[T], params []T, canceled *atomic.Int64, requeue bool) (err error) {
+	n := int64(len(params))
+	canceled.Add(-n)
+	task.counter.addPending(-n)
+	task.counter.addRunning(n)
+	var retry *RetryAfterError
 	defer func() {
-		e.counter.running.Add(-1)
-		e.counter.completed.Add(1)
-		if err := recover(); err != nil {
-			if task.recover != nil {
-				task.recover(param, err)
-			} else {
-				// default recover
-				buf := make([]byte, 64<<10)
-				buf = buf[:runtime.Stack(buf, false)]
-				fmt.Println("panic:", err, "\n"+string(buf))
+		task.counter.addRunning(-n)
+		if retry != nil {
+			// params go back to pending until requeue runs them again
+			task.counter.addPending(n)
+			canceled.Add(n)
+			return
+		}
+		task.counter.addCompleted(n)
+		if r := recover(); r != nil {
+			err = nil
+			if task.panicFails {
+				err = fmt.Errorf("%w: %v", ErrTaskPanic, r)
+				task.failItems(n, err)
+			}
+			for _, param := range params {
+				if task.recover != nil {
+					task.recover(param, r)
+				} else {
+					// default recover
+					buf := make([]byte, 64<<10)
+					buf = buf[:runtime.Stack(buf, false)]
+					fmt.Println("panic:", r, "\n"+string(buf))
+				}
 			}
 		}
 	}()
-	task.taskFunc(task.ctx, param)
-}
-
-// limitedRateLimitRun maximum qps is min(Task.maxConcurrency, Executor.limiter.capacity)
-func (e *Executor[T]) limitedRateLimitRun(task *Task[T]) {
-	wg := new(sync.WaitGroup)
-	taskLimiter := NewRateLimiter(min(task.maxConcurrency, e.limiter.Capacity()))
-	canceled := new(atomic.Int64)
-	canceled.Store(int64(len(task.param)))
-	defer func() {
-		e.counter.canceled.Add(canceled.Load())
-		e.counter.pending.Add(-canceled.Load())
-	}()
-	defer taskLimiter.Stop()
-	defer task.done()
-	defer e.picker.Remove(task.weightedItemId)
-	defer wg.Wait()
-
-	for _, param := range task.param {
-		select {
-		case <-taskLimiter.wait:
-		case <-e.stop:
-			return
-		case <-task.ctx.Done():
-			return
+	if task.batchFunc == nil {
+		err = task.taskFunc(task.runCtx, params[0])
+	} else {
+		err = task.batchFunc(task.runCtx, params)
+	}
+	if requeue {
+		if retry = retryAfter(err); retry != nil {
+			return retry
 		}
-		select {
-		case <-e.stop:
-			return
-		case <-task.ctx.Done():
-			return
-		case <-task.wait:
-			wg.Go(func() {
-				e.run(task, param, canceled)
-			})
+	}
+	if task.batchFunc == nil {
+		if err != nil {
+			task.failItems(1, err)
 		}
+		return err
 	}
-}
-
-// limitedConcurrentRun maximum concurrency is min(Task.maxConcurrency, Executor.limiter.capacity)
-func (e *Executor[T]) limitedConcurrentRun(task *Task[T]) {
-	wg := new(sync.WaitGroup)
-	idle := semaphore.NewWeighted(int64(min(task.maxConcurrency, e.limiter.Capacity())))
-	canceled := new(atomic.Int64)
-	canceled.Store(int64(len(task.param)))
-	defer func() {
-		e.counter.canceled.Add(canceled.Load())
-		e.counter.pending.Add(-canceled.Load())
-	}()
-	defer task.done()
-	defer e.picker.Remove(task.weightedItemId)
-	defer wg.Wait()
-
-	for _, param := range task.param {
-		if err := idle.Acquire(task.ctx, 1); err != nil {
-			return
+	if err != nil {
+		var batchErr *BatchError
+		if !errors.As(err, &batchErr) {
+			task.failItems(n, err)
+			return err
 		}
-		if err := e.idle.Acquire(task.ctx, 1); err != nil {
-			idle.Release(1)
-			return
+		if len(batchErr.Errs) == 0 {
+			return nil
 		}
-		select {
-		case <-e.stop:
-			idle.Release(1)
-			e.idle.Release(1)
-			return
-		case <-task.ctx.Done():
-			idle.Release(1)
-			e.idle.Release(1)
-			return
-		case <-task.wait:
-			wg.Go(func() {
-				e.run(task, param, canceled)
-				e.idle.Release(1)
-				idle.Release(1)
-			})
+		for _, itemErr := range batchErr.Errs {
+			task.failItems(1, itemErr)
 		}
 	}
+	return err
+}
+
+// failures is the number of params of a run which failed with err
+func failures(n int, err error) int {
+	var batchErr *BatchError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &batchErr):
+		return min(len(batchErr.Errs), n)
+	default:
+		return n
+	}
 }
 
-// unlimitedRateLimitRun maximum qps is Executor.limiter.capacity
-func (e *Executor[T]) unlimitedRateLimitRun(task *Task[T]) {
-	wg := new(sync.WaitGroup)
-	canceled := new(atomic.Int64)
-	canceled.Store(int64(len(task.param)))
-	defer func() {
-		e.counter.canceled.Add(canceled.Load())
-		e.counter.pending.Add(-canceled.Load())
-	}()
-	defer task.done()
-	defer e.picker.Remove(task.weightedItemId)
-	defer wg.Wait()
+// spawn runs the params of j in their own goroutine or hands them over to a pool worker if the executor has workers,
+// false if the executor stopped before a worker took them
+func (e *Executor[T]) spawn(j job[T]) bool {
+	if j.task.lc.pool == nil {
+		j.wg.Go(func() {
+			e.execute(j)
+		})
+		return true
+	}
+	j.wg.Add(1)
+	if j.task.lc.pool.submit(j) {
+		return true
+	}
+	j.wg.Done()
+	e.release(j.cost, j.idle)
+	return false
+}
+
+// execute runs a param and releases the concurrency slots it holds, a param asking to be retried after a while is
+// requeued unless it was requeued WithMaxRequeues times already
+func (e *Executor[T]) execute(j job[T]) {
+	start := time.Now()
+	requeue := e.options.maxRequeues < 0 || j.requeues < e.options.maxRequeues
+	err := e.run(j.task, j.params, j.canceled, requeue)
+	if retry := retryAfter(err); retry != nil && requeue {
+		e.requeue(j, retry)
+		return
+	}
+	if b := e.breaker(j.task, j.params); b != nil && b.record(len(j.params), failures(len(j.params), err), time.Since(start)) {
+		j.task.counter.addTrips(1)
+	}
+	e.resolve(j.task, j.index, err)
+	for i := range j.params {
+		e.checkpoint(j.task, j.index+i)
+	}
+	e.release(j.cost, j.idle)
+}
 
-	for _, param := range task.param {
-		select {
-		case <-e.stop:
-			return
-		case <-task.ctx.Done():
-			return
-		case <-task.wait:
-			wg.Go(func() {
-				e.run(task, param, canceled)
-			})
-		}
+// release frees the cost executor concurrency slots in ConcurrencyMode and the task concurrency slot if any
+func (e *Executor[T]) release(cost int, idle *semaphore.Weighted) {
+	if e.mode == ConcurrencyMode {
+		e.idle.Release(int64(cost))
 	}
+	if idle != nil {
+		idle.Release(1)
+	}
+}
+
+// limits returns the rate and the in-flight limit of task, WithMaxConcurrency is a rate limit in RateLimitMode and
+// an in-flight limit in ConcurrencyMode unless the limit is set explicitly
+func (e *Executor[T]) limits(task *Task[T]) (qps, inFlight int) {
+	qps, inFlight = task.maxQPS, task.maxInFlight
+	switch {
+	case task.maxConcurrency <= 0:
+	case e.mode == RateLimitMode && qps <= 0:
+		qps = task.maxConcurrency
+	case e.mode == ConcurrencyMode && inFlight <= 0:
+		inFlight = task.maxConcurrency
+	}
+	return qps, inFlight
 }
 
-// unlimitedConcurrentRun maximum concurrency is Executor.limiter.capacity
-func (e *Executor[T]) unlimitedConcurrentRun(task *Task[T]) {
+// runParams runs the params of task, each run takes in order, the task tokens being reclaimed while it blocks on
+// its own limits:
+//   - a token of the task rate limiter, at most min(qps, Executor.limiter.capacity) per second;
+//   - a task in-flight slot, at most min(inFlight, Executor.limiter.capacity) in ConcurrencyMode;
+//   - cost executor concurrency slots and a token in ConcurrencyMode, cost tokens in RateLimitMode.
+func (e *Executor[T]) runParams(task *Task[T], canceled *atomic.Int64) {
 	wg := new(sync.WaitGroup)
-	canceled := new(atomic.Int64)
-	canceled.Store(int64(len(task.param)))
-	defer func() {
-		e.counter.canceled.Add(canceled.Load())
-		e.counter.pending.Add(-canceled.Load())
-	}()
-	defer task.done()
-	defer e.picker.Remove(task.weightedItemId)
+	qps, inFlight := e.limits(task)
+	var taskLimiter *RateLimiter
+	if qps > 0 {
+		taskLimiter = NewRateLimiter(min(qps, task.lc.limiter.Capacity()))
+		defer taskLimiter.Stop()
+	}
+	var idle *semaphore.Weighted
+	if inFlight > 0 {
+		if e.mode == ConcurrencyMode {
+			inFlight = min(inFlight, task.lc.limiter.Capacity())
+		}
+		idle = semaphore.NewWeighted(int64(inFlight))
+	}
 	defer wg.Wait()
 
-	for _, param := range task.param {
-		if err := e.idle.Acquire(task.ctx, 1); err != nil {
-			return
+	// acquire takes the limits of a run and returns its cost, false once the task stopped, mu serializes the
+	// dispatch loop and the requeued params
+	var mu sync.Mutex
+	acquire := func(params []T) (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if taskLimiter != nil && !taskLimiter.tryTake(1) {
+			if e.block(task, func() error { return taskLimiter.take(1, task.lc.stop, task.ctx.Done()) }) != nil {
+				return 0, false
+			}
+		}
+		if idle != nil && !idle.TryAcquire(1) {
+			if e.block(task, func() error { return idle.Acquire(task.ctx, 1) }) != nil {
+				return 0, false
+			}
+		}
+		cost := task.cost(params)
+		e.checkDeadline(task, cost, len(params))
+		tokens := cost
+		if e.mode == ConcurrencyMode {
+			if err := e.idle.Acquire(task.ctx, int64(cost)); err != nil {
+				if idle != nil {
+					idle.Release(1)
+				}
+				return 0, false
+			}
+			tokens = 1
+		}
+		if !e.tokens(task, tokens) {
+			e.release(cost, idle)
+			return 0, false
 		}
-		select {
-		case <-e.stop:
-			e.idle.Release(1)
+		return cost, true
+	}
+	for i, params := range e.params(task, wg, canceled) {
+		cost, ok := acquire(params)
+		if !ok {
 			return
-		case <-task.ctx.Done():
-			e.idle.Release(1)
+		}
+		j := job[T]{task: task, index: i, params: params, cost: cost, canceled: canceled, idle: idle, wg: wg, acquire: acquire}
+		if !e.spawn(j) {
 			return
-		case <-task.wait:
-			wg.Go(func() {
-				e.run(task, param, canceled)
-				e.idle.Release(1)
-			})
 		}
 	}
 }
@@ -300,66 +487,125 @@ func (e *Executor[T]) Counter() *Counter {
 	return e.counter
 }
 
+// TenantCounter counts the params of the tasks of tenant, see TaskBuilder.WithTenant, it counts into Counter.
+// Tenant "" is the executor Counter itself.
+func (e *Executor[T]) TenantCounter(tenant string) *Counter {
+	if tenant == "" {
+		return e.counter
+	}
+	e.tenantsMu.Lock()
+	defer e.tenantsMu.Unlock()
+	c, ok := e.tenants[tenant]
+	if !ok {
+		c = newCounter(e.counter)
+		e.tenants[tenant] = c
+	}
+	return c
+}
+
+// Submit blocks according to the queue policy, tasks rejected by the queue fail with *RejectedError
 func (e *Executor[T]) Submit(tasks ...*Task[T]) *Future {
-	e.mu.Lock()
-	defer e.mu.Unlock()
-	if e.stopped {
-		return &Future{err: ErrExecutorStopped}
+	return e.submit(context.Background(), true, tasks)
+}
+
+// SubmitContext is Submit but a blocked submit gives up once ctx is done
+func (e *Executor[T]) SubmitContext(ctx context.Context, tasks ...*Task[T]) *Future {
+	return e.submit(ctx, true, tasks)
+}
+
+// TrySubmit never blocks, tasks fail with ErrQueueFull if the queue is full unless the queue policy is QueueDropOldest
+func (e *Executor[T]) TrySubmit(tasks ...*Task[T]) *Future {
+	return e.submit(context.Background(), false, tasks)
+}
+
+func (e *Executor[T]) submit(ctx context.Context, block bool, tasks []*Task[T]) *Future {
+	return e.accept(tasks, func(task *Task[T]) {
+		e.enqueue(ctx, task, block)
+	})
+}
+
+// accept prepares tasks and hands each of them over to enqueue unless the executor is stopped
+func (e *Executor[T]) accept(tasks []*Task[T], enqueue func(*Task[T])) *Future {
+	if e.IsStopped() {
+		f := failedFuture(ErrExecutorStopped)
+		for _, task := range tasks {
+			task.counter = newCounter(nil)
+			task.future = failedFuture(ErrExecutorStopped)
+			task.future.counter = task.counter
+			f.tasks = append(f.tasks, task.future)
+		}
+		return f
 	}
-	wg := new(sync.WaitGroup)
-	wg.Add(len(tasks))
-	cancelFuncs := make([]context.CancelFunc, 0, len(tasks))
+	futures := make([]*Future, 0, len(tasks))
 	for _, task := range tasks {
 		var cancel context.CancelFunc
-		task.wg = wg
 		task.ctx, cancel = context.WithCancel(task.ctx)
-		cancelFuncs = append(cancelFuncs, cancel)
-		e.counter.pending.Add(int64(len(task.param)))
-		if task.maxConcurrency > 0 {
-			task.wait = make(chan struct{}, min(task.weight, task.maxConcurrency, e.limiter.capacity))
-		} else {
-			task.wait = make(chan struct{}, min(task.weight, e.limiter.capacity))
+		// the deadline stops dispatching params, params already running keep a context without it
+		task.runCtx = task.ctx
+		if !task.deadline.IsZero() {
+			var stop context.CancelFunc
+			task.ctx, stop = context.WithDeadlineCause(task.ctx, task.deadline, ErrTaskDeadline)
+			cancelTask := cancel
+			cancel = func() {
+				stop()
+				cancelTask()
+			}
 		}
+		task.counter = newCounter(e.TenantCounter(task.tenant))
+		task.future = newFuture(cancel)
+		task.future.counter = task.counter
+		futures = append(futures, task.future)
+		task.counter.addPending(int64(len(task.param)))
+		if err := e.persist(task); err != nil {
+			e.reject(task, err)
+			continue
+		}
+		e.track(task)
+		enqueue(task)
+	}
+	return All(futures...)
+}
 
-		task.weightedItemId = e.picker.Add(task, int64(task.weight))
-		e.task <- task
+func (e *Executor[T]) enqueue(ctx context.Context, task *Task[T], block bool) {
+	dropped, err := e.current().queue.push(ctx, task, block)
+	if err != nil {
+		e.reject(task, err)
 	}
-	return &Future{wg: wg, cancelFuncs: cancelFuncs}
+	if dropped != nil {
+		e.reject(dropped, &RejectedError{Policy: QueueDropOldest, Err: ErrTaskDropped})
+	}
+}
+
+// reject fails a task which never reached dispatch
+func (e *Executor[T]) reject(task *Task[T], err error) {
+	defer e.untrack(task)
+	task.counter.addCanceled(int64(len(task.param)))
+	task.counter.addPending(-int64(len(task.param)))
+	task.err = err
+	e.settle(task)
+	task.done()
 }
 
 // GracefulStop no new tasks can be submitted after stop, all running tasks will wait to be completed until timeout
 func (e *Executor[T]) GracefulStop(timeout time.Duration) {
-	e.mu.Lock()
-	if e.stopped {
-		e.mu.Unlock()
-		return
-	}
-	e.stopped = true
-	close(e.task)
-	e.mu.Unlock()
-	defer e.limiter.Stop()
-	defer close(e.stop)
-	deadline := time.Now().Add(timeout)
-	for {
-		if time.Now().After(deadline) || e.runningTask.Load() == 0 {
-			return
-		}
-		time.Sleep(time.Second)
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	e.shutdown(ctx, true)
 }
 
 // Stop immediately stop
 func (e *Executor[T]) Stop() {
 	e.mu.Lock()
-	if e.stopped {
+	if e.state >= StateStopping {
 		e.mu.Unlock()
 		return
 	}
 	defer e.mu.Unlock()
-	e.stopped = true
-	close(e.task)
-	close(e.stop)
-	e.limiter.Stop()
+	e.state = StateStopped
+	e.lc.delayed.close()
+	e.lc.queue.close()
+	close(e.lc.stop)
+	e.lc.limiter.Stop()
 }
 
 func (e *Executor[T]) Wait(futures ...*Future) {
@@ -369,49 +615,86 @@ func (e *Executor[T]) Wait(futures ...*Future) {
 }
 
 func (e *Executor[T]) Pause() {
-	e.limiter.Pause()
+	e.current().limiter.Pause()
 }
 
 func (e *Executor[T]) Resume() {
-	e.limiter.Resume()
+	e.current().limiter.Resume()
 }
 
 func (e *Executor[T]) IsPaused() bool {
-	return e.limiter.IsPaused()
+	return e.current().limiter.IsPaused()
+}
+
+// IsBackingOff is true while a param which failed with *RetryAfterError holds the executor back
+func (e *Executor[T]) IsBackingOff() bool {
+	return e.current().limiter.BackingOff()
 }
 
+// SetWorkers resizes the worker pool, it is a no-op if the executor was not created WithWorkers
+func (e *Executor[T]) SetWorkers(workers int) {
+	if pool := e.current().pool; pool != nil {
+		pool.resize(workers)
+	}
+}
+
+// Workers returns the worker pool size, 0 means a goroutine per param
+func (e *Executor[T]) Workers() int {
+	if pool := e.current().pool; pool != nil {
+		return pool.Size()
+	}
+	return 0
+}
+
+// IsStopped is true from the beginning of Stop, GracefulStop or Shutdown until Restart
 func (e *Executor[T]) IsStopped() bool {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	return e.stopped
+	return e.state >= StateStopping
 }
 
-func NewExecutor[T any](capacity int, mode ExecutorMode) *Executor[T] {
+func NewExecutor[T any](capacity int, mode ExecutorMode, opts ...Option) *Executor[T] {
 	p := &Executor[T]{
-		mode:    mode,
-		limiter: NewRateLimiter(capacity),
-		task:    make(chan *Task[T], 64),
-		stop:    make(chan struct{}),
-		counter: &Counter{
-			running:   new(atomic.Int64),
-			pending:   new(atomic.Int64),
-			completed: new(atomic.Int64),
-			canceled:  new(atomic.Int64),
-		},
-		runningTask: new(atomic.Int64),
-		picker:      robinx.NewSmoothWeightedPicker[*Task[T]](),
+		mode:     mode,
+		options:  newOptions(opts...),
+		counter:  newCounter(nil),
+		active:   make(map[*Task[T]]struct{}),
+		tenants:  make(map[string]*Counter),
+		idleTask: make(chan struct{}, 1),
 	}
 	if mode == ConcurrencyMode {
 		p.idle = semaphore.NewWeighted(int64(capacity))
 	}
-	go p.start()
+	p.scheduler = NewTenantScheduler(p.options.tenants, func() Scheduler[T] {
+		return newScheduler[T](p.options.scheduling)
+	})
+	if p.options.scheduler != nil {
+		scheduler, ok := p.options.scheduler.(Scheduler[T])
+		if !ok {
+			panic(fmt.Sprintf("conrate: scheduler %T does not schedule tasks of %T", p.options.scheduler, *new(T)))
+		}
+		p.scheduler = scheduler
+	}
+	p.store = p.options.store
+	p.dedup = newDedup(p.options.dedupTTL)
+	p.breakers = newBreakers(p.options.breaker)
+	p.codec = JSONCodec[T]{}
+	if p.options.codec != nil {
+		codec, ok := p.options.codec.(Codec[T])
+		if !ok {
+			panic(fmt.Sprintf("conrate: codec %T does not encode %T", p.options.codec, *new(T)))
+		}
+		p.codec = codec
+	}
+	p.lc = p.newLifecycle(capacity, p.options.workers)
+	go p.start(p.lc)
 	return p
 }
 
-func NewConcurrentExecutor[T any](maxConcurrency int) *Executor[T] {
-	return NewExecutor[T](maxConcurrency, ConcurrencyMode)
+func NewConcurrentExecutor[T any](maxConcurrency int, opts ...Option) *Executor[T] {
+	return NewExecutor[T](maxConcurrency, ConcurrencyMode, opts...)
 }
 
-func NewRateLimitExecutor[T any](maxQPS int) *Executor[T] {
-	return NewExecutor[T](maxQPS, RateLimitMode)
+func NewRateLimitExecutor[T any](maxQPS int, opts ...Option) *Executor[T] {
+	return NewExecutor[T](maxQPS, RateLimitMode, opts...)
 }
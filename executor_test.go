@@ -3,6 +3,8 @@ package conrate
 import (
 	"context"
 	"errors"
+	"reflect"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -182,7 +184,7 @@ func TestRateLimitExecutorUnlimitedCompletesAll(t *testing.T) {
 }
 
 // TestRateLimitExecutorLimitedTaskConcurrency expects:
-//   - rate-limit mode with WithMaxConcurrency(5): all n params complete via limitedRateLimitRun.
+//   - rate-limit mode with WithMaxConcurrency(5): all n params complete under the task rate limit.
 func TestRateLimitExecutorLimitedTaskConcurrency(t *testing.T) {
 	p := NewRateLimitExecutor[int](100)
 	defer p.Stop()
@@ -203,6 +205,85 @@ func TestRateLimitExecutorLimitedTaskConcurrency(t *testing.T) {
 	}
 }
 
+// TestMaxQPSConcurrencyMode expects WithMaxQPS(5) to rate limit a task of a concurrency executor: 10 instant params
+// take >= 800ms once the 5 token burst is used.
+func TestMaxQPSConcurrencyMode(t *testing.T) {
+	p := NewConcurrentExecutor[int](50)
+	defer p.Stop()
+
+	start := time.Now()
+	f := p.Submit(NewTaskBuilder[int]().
+		WithTaskFunc(func(context.Context, int) {}).
+		WithMaxQPS(5).
+		BuildTask(ints(10)))
+	if err := f.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	if got := time.Since(start); got < 800*time.Millisecond {
+		t.Fatalf("10 params ran in %v at 5 qps, want >= 800ms", got)
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
+
+// TestMaxInFlightRateLimitMode expects WithMaxInFlight(2) to cap a task of a rate-limit executor at 2 params
+// running at once.
+func TestMaxInFlightRateLimitMode(t *testing.T) {
+	p := NewRateLimitExecutor[int](100)
+	defer p.Stop()
+
+	var running, maxRunning atomic.Int64
+	const n = 10
+	f := p.Submit(NewTaskBuilder[int]().
+		WithTaskFunc(func(context.Context, int) {
+			r := running.Add(1)
+			for {
+				m := maxRunning.Load()
+				if r <= m || maxRunning.CompareAndSwap(m, r) {
+					break
+				}
+			}
+			time.Sleep(30 * time.Millisecond)
+			running.Add(-1)
+		}).
+		WithMaxInFlight(2).
+		BuildTask(ints(n)))
+	if err := f.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	if got := maxRunning.Load(); got > 2 {
+		t.Fatalf("max running %d, want <= 2", got)
+	}
+	if got := f.Counter().Completed(); got != n {
+		t.Fatalf("Completed() = %d, want %d", got, n)
+	}
+}
+
+// TestThroughputHeterogeneous expects a rate-limit executor at 50 qps to start >= 80% of 50 params per second when a
+// heavy task is stuck on its in-flight limit: tokens it cannot take go to the light task instead of being dropped.
+func TestThroughputHeterogeneous(t *testing.T) {
+	p := NewRateLimitExecutor[int](50)
+	defer p.Stop()
+
+	var started atomic.Int64
+	capped := NewTaskBuilder[int]().WithWeight(10).WithMaxInFlight(1).WithTaskFunc(func(context.Context, int) {
+		started.Add(1)
+		time.Sleep(200 * time.Millisecond)
+	}).BuildTask(ints(100))
+	light := NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		started.Add(1)
+	}).BuildTask(ints(1000))
+	f := p.Submit(capped, light)
+	defer f.Cancel()
+
+	// skip the initial burst
+	waitUntil(t, 3*time.Second, func() bool { return started.Load() >= 50 })
+	from := started.Load()
+	time.Sleep(2 * time.Second)
+	if got := started.Load() - from; got < 80 {
+		t.Fatalf("%d params started in 2s at 50 qps, want >= 80", got)
+	}
+}
+
 // TestFutureCancel expects:
 //   - params not yet run after Cancel count toward Canceled;
 //   - completed + canceled == n, Pending/Running zero, and canceled > 0.
@@ -352,7 +433,8 @@ func TestGracefulStop(t *testing.T) {
 
 // TestTaskPanicRecover expects:
 //   - WithRecover invoked once per panicking param (3 times total);
-//   - panics still count as Completed; Pending/Running return to zero.
+//   - panics still count as Completed; Pending/Running return to zero;
+//   - panics handled by WithRecover of a WithTaskFunc task not to count as Failed nor to fail the Future.
 func TestTaskPanicRecover(t *testing.T) {
 	p := NewConcurrentExecutor[int](2)
 	defer p.Stop()
@@ -375,6 +457,164 @@ func TestTaskPanicRecover(t *testing.T) {
 	if got := p.Counter().Completed(); got != 3 {
 		t.Fatalf("Completed() = %d, want 3", got)
 	}
+	if got := p.Counter().Failed(); got != 0 {
+		t.Fatalf("Failed() = %d, want 0", got)
+	}
+	if err := f.Error(); err != nil {
+		t.Fatalf("Error() = %v, want nil", err)
+	}
+}
+
+// TestTaskFuncE expects:
+//   - params returning an error or panicking to count as Failed and Completed;
+//   - the Future to fail with an *ItemError holding the failed count and the first error.
+func TestTaskFuncE(t *testing.T) {
+	p := NewConcurrentExecutor[int](1)
+	defer p.Stop()
+
+	errOdd := errors.New("odd")
+	f := p.Submit(NewTaskBuilder[int]().
+		WithTaskFuncE(func(_ context.Context, i int) error {
+			switch {
+			case i == 4:
+				panic("boom")
+			case i%2 == 1:
+				return errOdd
+			}
+			return nil
+		}).
+		WithRecover(func(int, any) {}).
+		BuildTask(ints(6)))
+	p.Wait(f)
+
+	var itemErr *ItemError
+	if !errors.As(f.Error(), &itemErr) || !errors.Is(f.Error(), errOdd) {
+		t.Fatalf("Error() = %v, want *ItemError wrapping the first error", f.Error())
+	}
+	if itemErr.Failed != 4 {
+		t.Fatalf("ItemError.Failed = %d, want 4", itemErr.Failed)
+	}
+	if got := f.Counter().Failed(); got != 4 {
+		t.Fatalf("Failed() = %d, want 4", got)
+	}
+	if got := p.Counter().Completed(); got != 6 {
+		t.Fatalf("Completed() = %d, want 6", got)
+	}
+}
+
+// TestStreamTask expects:
+//   - a stream task to run every param received until its source is closed;
+//   - params received to count as pending;
+//   - canceling a stream task waiting for params to fail it with context.Canceled.
+func TestStreamTask(t *testing.T) {
+	p := NewRateLimitExecutor[int](100)
+	defer p.Stop()
+
+	var total atomic.Int64
+	builder := NewTaskBuilder[int]().WithTaskFunc(func(_ context.Context, i int) {
+		total.Add(int64(i))
+	})
+	source := make(chan int)
+	f := p.Submit(builder.BuildStreamTask(source))
+	for i := range 10 {
+		source <- i
+	}
+	close(source)
+	if err := f.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	if got := total.Load(); got != 45 {
+		t.Fatalf("sum of params %d, want 45", got)
+	}
+	if got := f.Counter().Completed(); got != 10 {
+		t.Fatalf("Completed() = %d, want 10", got)
+	}
+
+	f = p.Submit(builder.BuildStreamTask(make(chan int)))
+	f.Cancel()
+	if err := f.WaitTimeout(5 * time.Second); !errors.Is(err, context.Canceled) {
+		t.Fatalf("WaitTimeout() = %v, want context.Canceled", err)
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
+
+// TestBatching expects:
+//   - params to reach the batch handler in order by batches of at most maxSize;
+//   - a plain error to fail every item of its batch and a *BatchError only the items it holds.
+func TestBatching(t *testing.T) {
+	p := NewConcurrentExecutor[int](1)
+	defer p.Stop()
+
+	var batches [][]int
+	errBatch := errors.New("batch failed")
+	f := p.Submit(NewTaskBuilder[int]().
+		WithBatching(4, 0, func(_ context.Context, items []int) error {
+			batches = append(batches, slices.Clone(items))
+			switch len(batches) {
+			case 1:
+				return &BatchError{Errs: map[int]error{1: errBatch}}
+			case 3:
+				return errBatch
+			}
+			return nil
+		}).
+		BuildTask(ints(10)))
+	p.Wait(f)
+
+	if want := [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}, {8, 9}}; !reflect.DeepEqual(batches, want) {
+		t.Fatalf("batches = %v, want %v", batches, want)
+	}
+	if !errors.Is(f.Error(), errBatch) {
+		t.Fatalf("Error() = %v, want errBatch", f.Error())
+	}
+	if got := f.Counter().Failed(); got != 3 {
+		t.Fatalf("Failed() = %d, want 3", got)
+	}
+	if got := f.Counter().Completed(); got != 10 {
+		t.Fatalf("Completed() = %d, want 10", got)
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
+
+// TestBatchingStream expects a stream task to run a batch once maxSize params are received,
+// maxWait after its first param and when the source is closed.
+func TestBatchingStream(t *testing.T) {
+	p := NewRateLimitExecutor[int](100)
+	defer p.Stop()
+
+	batches := make(chan []int, 10)
+	source := make(chan int)
+	f := p.Submit(NewTaskBuilder[int]().
+		WithBatching(3, 50*time.Millisecond, func(_ context.Context, items []int) error {
+			batches <- items
+			return nil
+		}).
+		BuildStreamTask(source))
+
+	for i := range 4 {
+		source <- i
+	}
+	if got := <-batches; !slices.Equal(got, []int{0, 1, 2}) {
+		t.Fatalf("full batch = %v, want [0 1 2]", got)
+	}
+	start := time.Now()
+	if got := <-batches; !slices.Equal(got, []int{3}) {
+		t.Fatalf("batch after maxWait = %v, want [3]", got)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("batch after maxWait took %v", elapsed)
+	}
+	source <- 4
+	close(source)
+	if got := <-batches; !slices.Equal(got, []int{4}) {
+		t.Fatalf("batch on close = %v, want [4]", got)
+	}
+	if err := f.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	if got := f.Counter().Completed(); got != 5 {
+		t.Fatalf("Completed() = %d, want 5", got)
+	}
 }
 
 // TestMultipleSubmitWait expects both submits (5 + 7 params) to run for a total of 12 executions.
@@ -410,28 +650,58 @@ func TestBuildTasks(t *testing.T) {
 	}
 }
 
+// TestWeighted runs tasks weighted 10, 1 and 1 through every built-in Scheduler passed WithScheduler, logging the
+// params started per task, and expects all params to complete.
 func TestWeighted(t *testing.T) {
+	for _, sc := range schedulers {
+		t.Run(sc.name, func(t *testing.T) {
+			t.Parallel()
+			testWeighted(t, sc.new())
+		})
+	}
+}
+
+// TestWithSchedulerMismatch expects NewExecutor to panic on a scheduler of tasks of another param type.
+func TestWithSchedulerMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewExecutor did not panic")
+		}
+	}()
+	NewRateLimitExecutor[int](1, WithScheduler(NewFIFOScheduler[string]()))
+}
+
+func testWeighted(t *testing.T, scheduler Scheduler[int]) {
 	c1 := new(atomic.Int64)
 	c2 := new(atomic.Int64)
 	c3 := new(atomic.Int64)
-	executor := NewRateLimitExecutor[int](3)
+	executor := NewRateLimitExecutor[int](30, WithScheduler(scheduler))
+	defer executor.Stop()
+	stop := make(chan struct{})
+	defer close(stop)
 	go func() {
+		ticker := time.NewTicker(time.Second / 10)
+		defer ticker.Stop()
 		for {
-			time.Sleep(time.Second)
-			t.Log("c1", c1.Load(), "c2", c2.Load(), "c3", c3.Load(), executor.Counter().Running())
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				t.Log("c1", c1.Load(), "c2", c2.Load(), "c3", c3.Load(), executor.Counter().Running())
+			}
 		}
 	}()
-	task1 := NewTaskBuilder[int]().WithWeight(10).WithMaxConcurrency(5).WithTaskFunc(func(_ context.Context, _ int) {
+	task1 := NewTaskBuilder[int]().WithWeight(10).WithMaxConcurrency(50).WithTaskFunc(func(_ context.Context, _ int) {
 		c1.Add(1)
-		time.Sleep(time.Second)
+		time.Sleep(time.Second / 10)
 	}).BuildTask(ints(50))
-	task2 := NewTaskBuilder[int]().WithWeight(1).WithMaxConcurrency(5).WithTaskFunc(func(_ context.Context, _ int) {
+	task2 := NewTaskBuilder[int]().WithWeight(1).WithMaxConcurrency(50).WithTaskFunc(func(_ context.Context, _ int) {
 		c2.Add(1)
-		time.Sleep(time.Second)
+		time.Sleep(time.Second / 10)
 	}).BuildTask(ints(50))
-	task3 := NewTaskBuilder[int]().WithWeight(1).WithMaxConcurrency(5).WithTaskFunc(func(_ context.Context, _ int) {
+	task3 := NewTaskBuilder[int]().WithWeight(1).WithMaxConcurrency(50).WithTaskFunc(func(_ context.Context, _ int) {
 		c3.Add(1)
-		time.Sleep(time.Second)
+		time.Sleep(time.Second / 10)
 	}).BuildTask(ints(50))
 	// go func() {
 	// 	time.Sleep(2 * time.Second)
@@ -440,5 +710,7 @@ func TestWeighted(t *testing.T) {
 	// 	executor.Resume()
 	// }()
 	executor.Wait(executor.Submit(task2, task1, task3))
-	time.Sleep(3 * time.Second)
+	if got := executor.Counter().Completed(); got != 150 {
+		t.Fatalf("Completed() = %d, want 150", got)
+	}
 }
@@ -0,0 +1,190 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Future is the handle of submitted tasks, Future.Error is nil until Future.Done is closed unless submit failed
+type Future struct {
+	done        chan struct{}
+	cancelFuncs []context.CancelFunc
+	err         error
+	tasks       []*Future
+	counter     *Counter
+	atRisk      atomic.Bool
+}
+
+func (f *Future) Wait() {
+	if f.done != nil {
+		<-f.done
+	}
+}
+
+// WaitContext waits until the future is done or ctx is done, returns ctx.Err() if ctx is done first else Future.Error
+func (f *Future) WaitContext(ctx context.Context) error {
+	select {
+	case <-f.Done():
+		return f.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitTimeout waits at most timeout, returns context.DeadlineExceeded if the future is not done in time
+func (f *Future) WaitTimeout(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return f.WaitContext(ctx)
+}
+
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+func (f *Future) Cancel() {
+	for _, cancel := range f.cancelFuncs {
+		cancel()
+	}
+}
+
+func (f *Future) Error() error {
+	select {
+	case <-f.Done():
+		return f.err
+	default:
+		return nil
+	}
+}
+
+// Tasks returns the per task futures, each can be canceled, waited on and inspected separately
+func (f *Future) Tasks() []*Future {
+	return f.tasks
+}
+
+// Counter returns the param counter of a per task future, for other futures it is a snapshot sum of Future.Tasks
+func (f *Future) Counter() *Counter {
+	if f.counter != nil {
+		return f.counter
+	}
+	counters := make([]*Counter, 0, len(f.tasks))
+	for _, task := range f.tasks {
+		counters = append(counters, task.counter)
+	}
+	return sum(counters...)
+}
+
+// AtRisk is true once a task of the future built WithDeadline is estimated to miss its deadline: its pending params
+// cannot all start in time even if it got every token at the current capacity
+func (f *Future) AtRisk() bool {
+	if f.atRisk.Load() {
+		return true
+	}
+	for _, task := range f.tasks {
+		if task.atRisk.Load() {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Future) resolve(err error) {
+	f.err = err
+	close(f.done)
+}
+
+func newFuture(cancelFuncs ...context.CancelFunc) *Future {
+	return &Future{done: make(chan struct{}), cancelFuncs: cancelFuncs}
+}
+
+func failedFuture(err error) *Future {
+	f := newFuture()
+	f.resolve(err)
+	return f
+}
+
+func tasksOf(futures []*Future) []*Future {
+	var tasks []*Future
+	for _, future := range futures {
+		if future.counter != nil {
+			tasks = append(tasks, future)
+		} else {
+			tasks = append(tasks, future.tasks...)
+		}
+	}
+	return tasks
+}
+
+func cancelFuncsOf(futures []*Future) []context.CancelFunc {
+	cancelFuncs := make([]context.CancelFunc, 0, len(futures))
+	for _, future := range futures {
+		cancelFuncs = append(cancelFuncs, future.Cancel)
+	}
+	return cancelFuncs
+}
+
+// All is done when all futures are done, error is the joined error of all futures
+func All(futures ...*Future) *Future {
+	f := newFuture(cancelFuncsOf(futures)...)
+	f.tasks = tasksOf(futures)
+	go func() {
+		errs := make([]error, 0, len(futures))
+		for _, future := range futures {
+			future.Wait()
+			errs = append(errs, future.Error())
+		}
+		f.resolve(errors.Join(errs...))
+	}()
+	return f
+}
+
+// Any is done when the first future succeeds or all futures fail, error is the joined error of all futures if all fail
+func Any(futures ...*Future) *Future {
+	f := newFuture(cancelFuncsOf(futures)...)
+	f.tasks = tasksOf(futures)
+	if len(futures) == 0 {
+		f.resolve(nil)
+		return f
+	}
+	var mu sync.Mutex
+	var once sync.Once
+	errs := make([]error, 0, len(futures))
+	for _, future := range futures {
+		go func() {
+			future.Wait()
+			err := future.Error()
+			if err == nil {
+				once.Do(func() { f.resolve(nil) })
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+			if len(errs) == len(futures) {
+				once.Do(func() { f.resolve(errors.Join(errs...)) })
+			}
+		}()
+	}
+	return f
+}
+
+// Race is done when the first future is done, error is the error of that future
+func Race(futures ...*Future) *Future {
+	f := newFuture(cancelFuncsOf(futures)...)
+	f.tasks = tasksOf(futures)
+	if len(futures) == 0 {
+		f.resolve(nil)
+		return f
+	}
+	var once sync.Once
+	for _, future := range futures {
+		go func() {
+			future.Wait()
+			once.Do(func() { f.resolve(future.Error()) })
+		}()
+	}
+	return f
+}
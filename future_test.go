@@ -0,0 +1,244 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestFutureDone expects Done() to be closed once all params have run, so it can be used in select.
+func TestFutureDone(t *testing.T) {
+	p := NewConcurrentExecutor[int](4)
+	defer p.Stop()
+
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {}).BuildTask(ints(8)))
+	select {
+	case <-f.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for Done()")
+	}
+	if err := f.Error(); err != nil {
+		t.Fatalf("Error() = %v, want nil", err)
+	}
+}
+
+// TestFutureWaitContext expects:
+//   - WaitContext to return ctx.Err() while the task is still blocked;
+//   - WaitContext to return nil after the task completes.
+func TestFutureWaitContext(t *testing.T) {
+	p := NewConcurrentExecutor[int](4)
+	defer p.Stop()
+
+	release := make(chan struct{})
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		<-release
+	}).BuildTask(ints(1)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := f.WaitContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitContext() = %v, want context.DeadlineExceeded", err)
+	}
+
+	close(release)
+	if err := f.WaitContext(context.Background()); err != nil {
+		t.Fatalf("WaitContext() = %v, want nil", err)
+	}
+}
+
+// TestFutureWaitTimeout expects WaitTimeout to return context.DeadlineExceeded for a blocked task and nil once it is done.
+func TestFutureWaitTimeout(t *testing.T) {
+	p := NewConcurrentExecutor[int](4)
+	defer p.Stop()
+
+	release := make(chan struct{})
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		<-release
+	}).BuildTask(ints(1)))
+
+	if err := f.WaitTimeout(50 * time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitTimeout() = %v, want context.DeadlineExceeded", err)
+	}
+	close(release)
+	if err := f.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+}
+
+// TestFutureCancelError expects Error() to report context.Canceled when params were dropped by Cancel.
+func TestFutureCancelError(t *testing.T) {
+	p := NewConcurrentExecutor[int](2)
+	defer p.Stop()
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		started <- struct{}{}
+		<-release
+	}).BuildTask(ints(10)))
+
+	<-started
+	f.Cancel()
+	close(release)
+	f.Wait()
+	if err := f.Error(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Error() = %v, want context.Canceled", err)
+	}
+}
+
+// TestAll expects All to be done only after every future is done and to join their errors.
+func TestAll(t *testing.T) {
+	p := NewConcurrentExecutor[int](4)
+	defer p.Stop()
+
+	release := make(chan struct{})
+	builder := NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		<-release
+	})
+	f1 := p.Submit(builder.BuildTask(ints(1)))
+	f2 := p.Submit(builder.BuildTask(ints(1)))
+	stopped := NewConcurrentExecutor[int](1)
+	stopped.Stop()
+	f3 := stopped.Submit(builder.BuildTask(ints(1)))
+
+	all := All(f1, f2, f3)
+	select {
+	case <-all.Done():
+		t.Fatal("All() done before its futures")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	all.Wait()
+	if err := all.Error(); !errors.Is(err, ErrExecutorStopped) {
+		t.Fatalf("Error() = %v, want ErrExecutorStopped", err)
+	}
+}
+
+// TestAny expects:
+//   - Any to be done as soon as one future succeeds, ignoring failed ones;
+//   - Any to report the joined error when all futures fail.
+func TestAny(t *testing.T) {
+	p := NewConcurrentExecutor[int](4)
+	defer p.Stop()
+	stopped := NewConcurrentExecutor[int](1)
+	stopped.Stop()
+
+	release := make(chan struct{})
+	defer close(release)
+	builder := NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {})
+	blocked := NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		<-release
+	})
+
+	f := Any(stopped.Submit(builder.BuildTask(ints(1))), p.Submit(blocked.BuildTask(ints(1))), p.Submit(builder.BuildTask(ints(1))))
+	if err := f.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+
+	f = Any(stopped.Submit(builder.BuildTask(ints(1))), stopped.Submit(builder.BuildTask(ints(1))))
+	f.Wait()
+	if err := f.Error(); !errors.Is(err, ErrExecutorStopped) {
+		t.Fatalf("Error() = %v, want ErrExecutorStopped", err)
+	}
+}
+
+// TestRace expects Race to settle with the first done future, including a failed one.
+func TestRace(t *testing.T) {
+	p := NewConcurrentExecutor[int](4)
+	defer p.Stop()
+	stopped := NewConcurrentExecutor[int](1)
+	stopped.Stop()
+
+	release := make(chan struct{})
+	defer close(release)
+	blocked := NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		<-release
+	})
+
+	f := Race(p.Submit(blocked.BuildTask(ints(1))), stopped.Submit(blocked.BuildTask(ints(1))))
+	if err := f.WaitTimeout(5 * time.Second); !errors.Is(err, ErrExecutorStopped) {
+		t.Fatalf("WaitTimeout() = %v, want ErrExecutorStopped", err)
+	}
+}
+
+// TestCombinatorsEmpty expects All, Any and Race of no futures to be done immediately.
+func TestCombinatorsEmpty(t *testing.T) {
+	for _, f := range []*Future{All(), Any(), Race()} {
+		if err := f.WaitTimeout(time.Second); err != nil {
+			t.Fatalf("WaitTimeout() = %v, want nil", err)
+		}
+	}
+}
+
+// TestFutureTasks expects:
+//   - Tasks() to return one handle per submitted task;
+//   - canceling one task handle not to affect the other task;
+//   - per task counters to count their own params and the aggregate Counter() to sum them.
+func TestFutureTasks(t *testing.T) {
+	p := NewConcurrentExecutor[int](4)
+	defer p.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blocked := NewTaskBuilder[int]().WithTaskFunc(func(_ context.Context, i int) {
+		if i == 0 {
+			close(started)
+		}
+		<-release
+	})
+	free := NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {})
+
+	f := p.Submit(blocked.BuildTask(ints(20)), free.BuildTask(ints(5)))
+	tasks := f.Tasks()
+	if len(tasks) != 2 {
+		t.Fatalf("len(Tasks()) = %d, want 2", len(tasks))
+	}
+
+	<-started
+	tasks[0].Cancel()
+	close(release)
+	if err := tasks[1].WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("second task WaitTimeout() = %v, want nil", err)
+	}
+	if got := tasks[1].Counter().Completed(); got != 5 {
+		t.Fatalf("second task Completed() = %d, want 5", got)
+	}
+
+	f.Wait()
+	if err := tasks[0].Error(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("first task Error() = %v, want context.Canceled", err)
+	}
+	if !errors.Is(f.Error(), context.Canceled) {
+		t.Fatalf("Error() = %v, want context.Canceled", f.Error())
+	}
+	first := tasks[0].Counter()
+	if first.Canceled() == 0 || first.Completed()+first.Canceled() != 20 {
+		t.Fatalf("first task completed(%d) + canceled(%d), want 20 with some canceled", first.Completed(), first.Canceled())
+	}
+	total := f.Counter()
+	if total.Completed()+total.Canceled() != 25 || total.Pending() != 0 {
+		t.Fatalf("aggregate completed(%d) + canceled(%d) pending(%d), want 25 and 0 pending", total.Completed(), total.Canceled(), total.Pending())
+	}
+	if total.Canceled() != p.Counter().Canceled() {
+		t.Fatalf("aggregate Canceled() = %d, executor Canceled() = %d", total.Canceled(), p.Counter().Canceled())
+	}
+}
+
+// TestFutureTasksAfterStop expects every task handle to fail with ErrExecutorStopped when submitted to a stopped executor.
+func TestFutureTasksAfterStop(t *testing.T) {
+	p := NewConcurrentExecutor[int](4)
+	p.Stop()
+
+	builder := NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {})
+	f := p.Submit(builder.BuildTasks(ints(1), ints(2))...)
+	if len(f.Tasks()) != 2 {
+		t.Fatalf("len(Tasks()) = %d, want 2", len(f.Tasks()))
+	}
+	for _, task := range f.Tasks() {
+		if !errors.Is(task.Error(), ErrExecutorStopped) {
+			t.Fatalf("task Error() = %v, want ErrExecutorStopped", task.Error())
+		}
+	}
+}
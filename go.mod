@@ -2,9 +2,8 @@ module github.com/riete/conrate
 
 go 1.25.9
 
-require golang.org/x/time v0.15.0
-
 require (
-	github.com/riete/robinx v0.0.5 // indirect
-	golang.org/x/sync v0.21.0 // indirect
+	github.com/riete/robinx v0.0.5
+	golang.org/x/sync v0.21.0
+	golang.org/x/time v0.15.0
 )
@@ -0,0 +1,249 @@
+// Package grpclimit limits gRPC calls with conrate limiters, on the client and on the server side. It is a module of
+// its own so that conrate does not depend on gRPC, it requires a published version of conrate and the go.work at the
+// repository root builds it against the checkout. go test ./... at the root does not run its tests, run them from
+// its directory.
+package grpclimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/riete/conrate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// KeyFunc returns the key of a call to the full method name
+type KeyFunc func(ctx context.Context, method string) string
+
+// Method keys a call by its full method name
+func Method(_ context.Context, method string) string {
+	return method
+}
+
+// IncomingMetadata keys a call by the first value of its incoming metadata name, for server interceptors
+func IncomingMetadata(name string) KeyFunc {
+	return func(ctx context.Context, _ string) string {
+		md, _ := metadata.FromIncomingContext(ctx)
+		return first(md, name)
+	}
+}
+
+// OutgoingMetadata keys a call by the first value of its outgoing metadata name, for client interceptors. A call
+// made by a server handler is not keyed by the metadata of the call the handler serves.
+func OutgoingMetadata(name string) KeyFunc {
+	return func(ctx context.Context, _ string) string {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		return first(md, name)
+	}
+}
+
+func first(md metadata.MD, name string) string {
+	if values := md.Get(name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// inFlightRetry is the retry delay told to a call rejected for lack of an in-flight slot, when one frees up is unknown
+const inFlightRetry = time.Second
+
+// slots are the in-flight slots of each key
+type slots struct {
+	max   int
+	used  map[string]int
+	freed chan struct{}
+	mu    sync.Mutex
+}
+
+// acquire takes a slot of key, waiting up to maxWait for one
+func (s *slots) acquire(ctx context.Context, key string, maxWait time.Duration) bool {
+	var expired <-chan time.Time
+	for {
+		s.mu.Lock()
+		if s.used[key] < s.max {
+			s.used[key]++
+			s.mu.Unlock()
+			return true
+		}
+		freed := s.freed
+		s.mu.Unlock()
+		if maxWait <= 0 {
+			return false
+		}
+		if expired == nil {
+			timer := time.NewTimer(maxWait)
+			defer timer.Stop()
+			expired = timer.C
+		}
+		select {
+		case <-freed:
+		case <-expired:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+func (s *slots) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.used[key]--; s.used[key] <= 0 {
+		delete(s.used, key)
+	}
+	close(s.freed)
+	s.freed = make(chan struct{})
+}
+
+// Gate lets a call through once it took a token of its limiter and an in-flight slot, else it fails the call with
+// codes.ResourceExhausted and an errdetails.RetryInfo telling when to retry
+type Gate struct {
+	options *options
+	slots   *slots
+}
+
+// acquire gates a call to method, release frees its in-flight slot
+func (g *Gate) acquire(ctx context.Context, method string) (release func(), err error) {
+	o := g.options
+	if limiter := g.limiter(ctx, method); limiter != nil {
+		if ok, wait := limiter.TryTake(); !ok {
+			if wait > o.queue {
+				return nil, exhausted("rate limit exceeded", wait)
+			}
+			if err := queue(ctx, limiter, o.queue); err != nil {
+				if errors.Is(err, conrate.ErrLimiterStopped) {
+					return nil, status.Error(codes.Unavailable, err.Error())
+				}
+				return nil, exhausted("rate limit exceeded", wait)
+			}
+		}
+	}
+	if g.slots == nil {
+		return func() {}, nil
+	}
+	key := o.inFlightKey(ctx, method)
+	if !g.slots.acquire(ctx, key, o.queue) {
+		return nil, exhausted("too many calls in flight", inFlightRetry)
+	}
+	var once sync.Once
+	return func() { once.Do(func() { g.slots.release(key) }) }, nil
+}
+
+// limiter returns the limiter of a call, nil without WithLimiter or WithKeyedLimiter
+func (g *Gate) limiter(ctx context.Context, method string) *conrate.RateLimiter {
+	switch o := g.options; {
+	case o.keyed != nil:
+		return o.keyed.Limiter(o.key(ctx, method))
+	default:
+		return o.limiter
+	}
+}
+
+// queue waits up to maxWait for a token of limiter
+func queue(ctx context.Context, limiter *conrate.RateLimiter, maxWait time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, maxWait)
+	defer cancel()
+	return limiter.Take(ctx)
+}
+
+// exhausted is a codes.ResourceExhausted error asking to retry after wait
+func exhausted(msg string, wait time.Duration) error {
+	st := status.New(codes.ResourceExhausted, msg)
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// RetryDelay returns the delay of the errdetails.RetryInfo of err, false if it has none
+func RetryDelay(err error) (time.Duration, bool) {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			return info.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+func (g *Gate) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		release, err := g.acquire(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return handler(ctx, req)
+	}
+}
+
+func (g *Gate) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		release, err := g.acquire(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return handler(srv, ss)
+	}
+}
+
+func (g *Gate) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		release, err := g.acquire(ctx, method)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor holds the in-flight slot of a stream until RecvMsg fails, the response of a stream
+// without server streaming is received or ctx is done
+func (g *Gate) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		release, err := g.acquire(ctx, method)
+		if err != nil {
+			return nil, err
+		}
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		stop := context.AfterFunc(ctx, release)
+		return &clientStream{ClientStream: cs, desc: desc, release: func() {
+			stop()
+			release()
+		}}, nil
+	}
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	desc    *grpc.StreamDesc
+	release func()
+}
+
+func (c *clientStream) RecvMsg(m any) error {
+	err := c.ClientStream.RecvMsg(m)
+	if err != nil || !c.desc.ServerStreams {
+		c.release()
+	}
+	return err
+}
+
+func New(opts ...Option) *Gate {
+	g := &Gate{options: newOptions(opts...)}
+	if g.options.maxInFlight > 0 {
+		g.slots = &slots{max: g.options.maxInFlight, used: make(map[string]int), freed: make(chan struct{})}
+	}
+	return g
+}
@@ -0,0 +1,196 @@
+package grpclimit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/riete/conrate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial serves the health service in process with the server options and returns a client dialed with the
+// client options
+func dial(t *testing.T, server []grpc.ServerOption, client ...grpc.DialOption) healthpb.HealthClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(server...)
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go func() {
+		_ = s.Serve(listener)
+	}()
+	t.Cleanup(s.Stop)
+	client = append(client,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient("passthrough:///bufconn", client...)
+	if err != nil {
+		t.Fatalf("NewClient() = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func check(ctx context.Context, client healthpb.HealthClient) error {
+	_, err := client.Check(ctx, new(healthpb.HealthCheckRequest))
+	return err
+}
+
+// watch opens a Watch stream and receives its first response, the stream stays in flight until ctx is done
+func watch(ctx context.Context, client healthpb.HealthClient) error {
+	stream, err := client.Watch(ctx, new(healthpb.HealthCheckRequest))
+	if err != nil {
+		return err
+	}
+	_, err = stream.Recv()
+	return err
+}
+
+// assertExhausted fails unless err is codes.ResourceExhausted with a retry delay in (0, max]
+func assertExhausted(t *testing.T, err error, max time.Duration) {
+	t.Helper()
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("err = %v, want ResourceExhausted", err)
+	}
+	if delay, ok := RetryDelay(err); !ok || delay <= 0 || delay > max {
+		t.Fatalf("RetryDelay() = %v, %v, want a delay in (0, %v]", delay, ok, max)
+	}
+}
+
+// TestUnaryServerInterceptor expects:
+//   - calls within the burst of the limiter to pass;
+//   - the next one to fail with ResourceExhausted and the wait for a token as retry delay;
+//   - WithQueue to make a call wait for a token instead.
+func TestUnaryServerInterceptor(t *testing.T) {
+	gate := New(WithLimiter(conrate.NewRateLimiter(10)))
+	client := dial(t, []grpc.ServerOption{grpc.UnaryInterceptor(gate.UnaryServerInterceptor())})
+	ctx := context.Background()
+	for i := range 10 {
+		if err := check(ctx, client); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	assertExhausted(t, check(ctx, client), 100*time.Millisecond)
+
+	gate = New(WithLimiter(conrate.NewRateLimiter(10)), WithQueue(200*time.Millisecond))
+	client = dial(t, []grpc.ServerOption{grpc.UnaryInterceptor(gate.UnaryServerInterceptor())})
+	for i := range 11 {
+		if err := check(ctx, client); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+}
+
+// TestKeyedLimiter expects each metadata value and each method to have its own limiter.
+func TestKeyedLimiter(t *testing.T) {
+	gate := New(WithKeyedLimiter(conrate.NewKeyedLimiter(1), IncomingMetadata("tenant")))
+	client := dial(t, []grpc.ServerOption{grpc.UnaryInterceptor(gate.UnaryServerInterceptor())})
+	a := metadata.AppendToOutgoingContext(context.Background(), "tenant", "a")
+	b := metadata.AppendToOutgoingContext(context.Background(), "tenant", "b")
+	if err := check(a, client); err != nil {
+		t.Fatalf("first call of a: %v", err)
+	}
+	assertExhausted(t, check(a, client), time.Second)
+	if err := check(b, client); err != nil {
+		t.Fatalf("first call of b: %v", err)
+	}
+
+	gate = New(WithKeyedLimiter(conrate.NewKeyedLimiter(1), nil))
+	client = dial(t, []grpc.ServerOption{
+		grpc.UnaryInterceptor(gate.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(gate.StreamServerInterceptor()),
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := check(ctx, client); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if err := watch(ctx, client); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+}
+
+// TestMetadataKeys expects IncomingMetadata and OutgoingMetadata to read their side only, a client call made by a
+// server handler being keyed by its own outgoing metadata.
+func TestMetadataKeys(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("tenant", "upstream"))
+	ctx = metadata.AppendToOutgoingContext(ctx, "tenant", "downstream")
+	if got := OutgoingMetadata("tenant")(ctx, ""); got != "downstream" {
+		t.Fatalf("OutgoingMetadata() = %q, want downstream", got)
+	}
+	if got := IncomingMetadata("tenant")(ctx, ""); got != "upstream" {
+		t.Fatalf("IncomingMetadata() = %q, want upstream", got)
+	}
+	if got := OutgoingMetadata("tenant")(context.Background(), ""); got != "" {
+		t.Fatalf("OutgoingMetadata() = %q, want empty", got)
+	}
+}
+
+// TestStreamServerInterceptor expects a stream to hold its in-flight slot until it ends.
+func TestStreamServerInterceptor(t *testing.T) {
+	gate := New(WithMaxInFlight(1, nil))
+	client := dial(t, []grpc.ServerOption{grpc.StreamInterceptor(gate.StreamServerInterceptor())})
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := watch(ctx, client); err != nil {
+		t.Fatalf("first Watch: %v", err)
+	}
+	assertExhausted(t, watch(context.Background(), client), time.Second)
+	cancel()
+	waitUntil(t, time.Second, func() bool {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		return watch(ctx, client) == nil
+	})
+}
+
+// TestClientInterceptors expects:
+//   - the client to fail calls beyond its limiter without sending them;
+//   - a client stream to hold its in-flight slot until its context is done.
+func TestClientInterceptors(t *testing.T) {
+	gate := New(WithLimiter(conrate.NewRateLimiter(1)), WithMaxInFlight(1, Method))
+	client := dial(t, nil,
+		grpc.WithUnaryInterceptor(gate.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(gate.StreamClientInterceptor()))
+	if err := check(context.Background(), client); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	assertExhausted(t, check(context.Background(), client), time.Second)
+
+	gate = New(WithMaxInFlight(1, Method))
+	client = dial(t, nil,
+		grpc.WithUnaryInterceptor(gate.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(gate.StreamClientInterceptor()))
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := watch(ctx, client); err != nil {
+		t.Fatalf("first Watch: %v", err)
+	}
+	assertExhausted(t, watch(context.Background(), client), time.Second)
+	if err := check(context.Background(), client); err != nil {
+		t.Fatalf("Check beside the Watch of another method: %v", err)
+	}
+	cancel()
+	waitUntil(t, time.Second, func() bool {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		return watch(ctx, client) == nil
+	})
+}
+
+func waitUntil(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
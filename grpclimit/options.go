@@ -0,0 +1,66 @@
+package grpclimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/riete/conrate"
+)
+
+type Option func(*options)
+
+type options struct {
+	limiter     *conrate.RateLimiter
+	keyed       *conrate.KeyedLimiter
+	key         KeyFunc
+	maxInFlight int
+	inFlightKey KeyFunc
+	queue       time.Duration
+}
+
+// WithLimiter makes every call take a token of limiter
+func WithLimiter(limiter *conrate.RateLimiter) Option {
+	return func(o *options) {
+		o.limiter = limiter
+	}
+}
+
+// WithKeyedLimiter makes a call take a token of the limiter of its key, Method if key is nil.
+// It replaces WithLimiter.
+func WithKeyedLimiter(limiter *conrate.KeyedLimiter, key KeyFunc) Option {
+	return func(o *options) {
+		o.keyed = limiter
+		o.key = key
+		if key == nil {
+			o.key = Method
+		}
+	}
+}
+
+// WithMaxInFlight limits the calls of a key running at the same time, of all calls if key is nil. A stream is in
+// flight until it ends.
+func WithMaxInFlight(maxInFlight int, key KeyFunc) Option {
+	return func(o *options) {
+		o.maxInFlight = maxInFlight
+		o.inFlightKey = key
+		if key == nil {
+			o.inFlightKey = func(context.Context, string) string { return "" }
+		}
+	}
+}
+
+// WithQueue makes a call without a token or an in-flight slot wait up to maxWait for one instead of failing at
+// once, a call which would wait longer for a token fails at once
+func WithQueue(maxWait time.Duration) Option {
+	return func(o *options) {
+		o.queue = maxWait
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
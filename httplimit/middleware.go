@@ -0,0 +1,94 @@
+package httplimit
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riete/conrate"
+)
+
+// Middleware limits the requests of a handler to the tokens of limiter, see KeyedMiddleware
+func Middleware(limiter *conrate.RateLimiter, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	return middleware(func(*http.Request) *conrate.RateLimiter { return limiter }, opts)
+}
+
+// KeyedMiddleware limits the requests of a handler to the tokens of the limiter of their key, e.g. ClientIP,
+// Header or APIKey. A request without a token is answered with 429 and Retry-After, or waits up to the WithQueue
+// duration for one. Every response tells the limit of the key with the RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset and RateLimit-Policy headers of the IETF RateLimit header fields draft.
+func KeyedMiddleware(limiter *conrate.KeyedLimiter, key func(*http.Request) string, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	return middleware(func(r *http.Request) *conrate.RateLimiter { return limiter.Limiter(key(r)) }, opts)
+}
+
+func middleware(limiter func(*http.Request) *conrate.RateLimiter, opts []MiddlewareOption) func(http.Handler) http.Handler {
+	o := newMiddlewareOptions(opts...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := limiter(r)
+			ok, wait := l.TryTake()
+			if !ok && wait <= o.queue {
+				ok = queue(r.Context(), l, o.queue) == nil
+			}
+			headers(w.Header(), l)
+			if !ok {
+				// a wait of a fraction of a second is rounded up, a client retrying earlier is rejected again
+				w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(wait.Seconds())), 1)))
+				o.reject.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// queue waits up to maxWait for a token of l
+func queue(ctx context.Context, l *conrate.RateLimiter, maxWait time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, maxWait)
+	defer cancel()
+	return l.Take(ctx)
+}
+
+// headers sets the RateLimit header fields of l, a bucket of capacity tokens refilled in a second
+func headers(h http.Header, l *conrate.RateLimiter) {
+	capacity, available := l.Capacity(), l.Available()
+	reset := 0
+	if capacity > 0 && available < capacity {
+		reset = int(math.Ceil(float64(capacity-available) / float64(capacity)))
+	}
+	h.Set("RateLimit-Limit", strconv.Itoa(capacity))
+	h.Set("RateLimit-Remaining", strconv.Itoa(available))
+	h.Set("RateLimit-Reset", strconv.Itoa(reset))
+	h.Set("RateLimit-Policy", strconv.Itoa(capacity)+";w=1")
+}
+
+// ClientIP keys a request by the IP of its remote address, proxy headers are not trusted
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Header keys a request by the value of its header name
+func Header(name string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// APIKey keys a request by its X-API-Key header, else by the bearer token of its Authorization header
+func APIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return ""
+}
@@ -0,0 +1,123 @@
+package httplimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/riete/conrate"
+)
+
+var ok = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+// serve sends a GET request with header to h and returns the response
+func serve(h http.Handler, remote string, header http.Header) *http.Response {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remote
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w.Result()
+}
+
+// TestMiddleware expects:
+//   - requests within the burst to pass with RateLimit headers counting down;
+//   - the next request to get 429 with Retry-After and RateLimit-Remaining 0.
+func TestMiddleware(t *testing.T) {
+	h := Middleware(conrate.NewRateLimiter(3))(ok)
+	for i := range 3 {
+		resp := serve(h, "192.0.2.1:1234", nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i, resp.StatusCode)
+		}
+		if got, want := resp.Header.Get("RateLimit-Remaining"), strconv.Itoa(2-i); got != want {
+			t.Fatalf("request %d: RateLimit-Remaining %s, want %s", i, got, want)
+		}
+		if got := resp.Header.Get("RateLimit-Limit"); got != "3" {
+			t.Fatalf("RateLimit-Limit %s, want 3", got)
+		}
+		if got := resp.Header.Get("RateLimit-Policy"); got != "3;w=1" {
+			t.Fatalf("RateLimit-Policy %s, want 3;w=1", got)
+		}
+	}
+	resp := serve(h, "192.0.2.1:1234", nil)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want 429", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") != "1" || resp.Header.Get("RateLimit-Remaining") != "0" || resp.Header.Get("RateLimit-Reset") != "1" {
+		t.Fatalf("Retry-After %q, RateLimit-Remaining %q, RateLimit-Reset %q, want 1, 0 and 1",
+			resp.Header.Get("Retry-After"), resp.Header.Get("RateLimit-Remaining"), resp.Header.Get("RateLimit-Reset"))
+	}
+}
+
+// TestKeyedMiddleware expects each key to have its own limit for ClientIP, Header and APIKey.
+func TestKeyedMiddleware(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		key  func(*http.Request) string
+		a, b http.Header
+		ra   string
+		rb   string
+	}{
+		{name: "ClientIP", key: ClientIP, ra: "192.0.2.1:1", rb: "192.0.2.2:1"},
+		{name: "Header", key: Header("X-Tenant"), a: http.Header{"X-Tenant": {"a"}}, b: http.Header{"X-Tenant": {"b"}}},
+		{name: "APIKey", key: APIKey, a: http.Header{"X-Api-Key": {"a"}}, b: http.Header{"Authorization": {"Bearer b"}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := KeyedMiddleware(conrate.NewKeyedLimiter(1), tt.key)(ok)
+			if serve(h, tt.ra, tt.a).StatusCode != http.StatusOK {
+				t.Fatal("first request of a rejected")
+			}
+			if serve(h, tt.ra, tt.a).StatusCode != http.StatusTooManyRequests {
+				t.Fatal("second request of a passed")
+			}
+			if serve(h, tt.rb, tt.b).StatusCode != http.StatusOK {
+				t.Fatal("first request of b rejected")
+			}
+		})
+	}
+}
+
+// TestMiddlewareQueue expects:
+//   - a request to wait for a token within the WithQueue duration instead of being rejected;
+//   - a request which would wait longer to be rejected at once.
+func TestMiddlewareQueue(t *testing.T) {
+	h := Middleware(conrate.NewRateLimiter(10), WithQueue(150*time.Millisecond))(ok)
+	for range 10 {
+		serve(h, "192.0.2.1:1", nil)
+	}
+	start := time.Now()
+	if resp := serve(h, "192.0.2.1:1", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200 after queueing", resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("queued %v, want about 100ms", elapsed)
+	}
+
+	limiter := conrate.NewRateLimiter(10)
+	limiter.Backoff(time.Hour)
+	start = time.Now()
+	resp := serve(Middleware(limiter, WithQueue(time.Second))(ok), "192.0.2.1:1", nil)
+	if resp.StatusCode != http.StatusTooManyRequests || time.Since(start) > 100*time.Millisecond {
+		t.Fatalf("status %d after %v, want 429 at once", resp.StatusCode, time.Since(start))
+	}
+	if got := resp.Header.Get("Retry-After"); got != "3600" {
+		t.Fatalf("Retry-After %s, want 3600", got)
+	}
+}
+
+// TestMiddlewareRejectHandler expects WithRejectHandler to answer rejected requests.
+func TestMiddlewareRejectHandler(t *testing.T) {
+	limiter := conrate.NewRateLimiter(1)
+	limiter.Pause()
+	h := Middleware(limiter, WithRejectHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})))(ok)
+	if resp := serve(h, "192.0.2.1:1", nil); resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("status %d with Retry-After %q, want 503 with Retry-After", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+}
@@ -0,0 +1,113 @@
+package httplimit
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/riete/conrate"
+)
+
+type TransportOption func(*transportOptions)
+
+type transportOptions struct {
+	limiter      *conrate.RateLimiter
+	keyed        *conrate.KeyedLimiter
+	key          func(*http.Request) string
+	maxInFlight  int
+	retries      int
+	retryWait    time.Duration
+	maxRetryWait time.Duration
+}
+
+// WithLimiter makes every request take a token of limiter
+func WithLimiter(limiter *conrate.RateLimiter) TransportOption {
+	return func(o *transportOptions) {
+		o.limiter = limiter
+	}
+}
+
+// WithKeyedLimiter makes a request take a token of the limiter of its key, the host of the request if key is nil.
+// It replaces WithLimiter.
+func WithKeyedLimiter(limiter *conrate.KeyedLimiter, key func(*http.Request) string) TransportOption {
+	return func(o *transportOptions) {
+		o.keyed = limiter
+		o.key = key
+		if key == nil {
+			o.key = Host
+		}
+	}
+}
+
+// WithMaxInFlight limits the requests sent at the same time, unlimited by default
+func WithMaxInFlight(maxInFlight int) TransportOption {
+	return func(o *transportOptions) {
+		o.maxInFlight = maxInFlight
+	}
+}
+
+// WithRetries sets how many times an idempotent request answered with 429 or 503 is sent again, 3 by default
+func WithRetries(retries int) TransportOption {
+	return func(o *transportOptions) {
+		o.retries = retries
+	}
+}
+
+// WithRetryWait sets the wait before a retry when the response has no Retry-After, 1s by default
+func WithRetryWait(wait time.Duration) TransportOption {
+	return func(o *transportOptions) {
+		o.retryWait = wait
+	}
+}
+
+// WithMaxRetryWait sets the longest Retry-After waited for, a longer one returns the response, 30s by default
+func WithMaxRetryWait(wait time.Duration) TransportOption {
+	return func(o *transportOptions) {
+		o.maxRetryWait = wait
+	}
+}
+
+// Host keys a request by the host of its URL
+func Host(req *http.Request) string {
+	return req.URL.Host
+}
+
+func newTransportOptions(opts ...TransportOption) *transportOptions {
+	o := &transportOptions{retries: 3, retryWait: time.Second, maxRetryWait: 30 * time.Second}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+type MiddlewareOption func(*middlewareOptions)
+
+type middlewareOptions struct {
+	queue  time.Duration
+	reject http.Handler
+}
+
+// WithQueue makes a request without a token wait up to maxWait for one instead of being rejected, a request which
+// would wait longer is rejected at once
+func WithQueue(maxWait time.Duration) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.queue = maxWait
+	}
+}
+
+// WithRejectHandler replaces the handler of rejected requests, which answers 429 Too Many Requests by default.
+// Retry-After and the RateLimit headers are set before it is called.
+func WithRejectHandler(reject http.Handler) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.reject = reject
+	}
+}
+
+func newMiddlewareOptions(opts ...MiddlewareOption) *middlewareOptions {
+	o := &middlewareOptions{reject: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+	})}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
@@ -0,0 +1,207 @@
+// Package httplimit limits outbound and inbound HTTP requests with conrate limiters.
+package httplimit
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/riete/conrate"
+	"golang.org/x/sync/semaphore"
+)
+
+// Metrics counts the requests of a Transport
+type Metrics struct {
+	requests  atomic.Int64
+	inFlight  atomic.Int64
+	throttled atomic.Int64
+	retries   atomic.Int64
+	errors    atomic.Int64
+}
+
+// Requests counts the requests sent to the base transport, retries included
+func (m *Metrics) Requests() int64 {
+	return m.requests.Load()
+}
+
+func (m *Metrics) InFlight() int64 {
+	return m.inFlight.Load()
+}
+
+// Throttled counts the responses with status 429 or 503
+func (m *Metrics) Throttled() int64 {
+	return m.throttled.Load()
+}
+
+func (m *Metrics) Retries() int64 {
+	return m.retries.Load()
+}
+
+// Errors counts the requests which failed without a response, waiting for a limiter included
+func (m *Metrics) Errors() int64 {
+	return m.errors.Load()
+}
+
+// Transport is an http.RoundTripper which waits for a token of its limiter and an in-flight slot before sending a
+// request to its base transport. Idempotent requests answered with 429 or 503 are sent again after Retry-After and
+// the limiter of the request backs off meanwhile, so other requests to the same key slow down too.
+type Transport struct {
+	base     http.RoundTripper
+	options  *transportOptions
+	inFlight *semaphore.Weighted
+	metrics  *Metrics
+}
+
+func (t *Transport) Metrics() *Metrics {
+	return t.metrics
+}
+
+// limiter returns the limiter of req, nil without WithLimiter or WithKeyedLimiter
+func (t *Transport) limiter(req *http.Request) *conrate.RateLimiter {
+	switch o := t.options; {
+	case o.keyed != nil:
+		return o.keyed.Limiter(o.key(req))
+	default:
+		return o.limiter
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limiter := t.limiter(req)
+	for attempt := 0; ; attempt++ {
+		resp, err := t.send(req, limiter)
+		if err != nil {
+			t.metrics.errors.Add(1)
+			return nil, err
+		}
+		if !throttled(resp) {
+			return resp, nil
+		}
+		t.metrics.throttled.Add(1)
+		after := retryAfter(resp, t.options.retryWait)
+		if attempt >= t.options.retries || after > t.options.maxRetryWait || !idempotent(req) {
+			return resp, nil
+		}
+		if req, err = rewind(req); err != nil {
+			return resp, nil
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if limiter != nil {
+			limiter.Backoff(after)
+		}
+		t.metrics.retries.Add(1)
+		timer := time.NewTimer(after)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			closeBody(req)
+			t.metrics.errors.Add(1)
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// send waits for a token of limiter and an in-flight slot and sends req to the base transport, the body of req is
+// closed if it is not sent
+func (t *Transport) send(req *http.Request, limiter *conrate.RateLimiter) (*http.Response, error) {
+	if limiter != nil {
+		if err := limiter.Take(req.Context()); err != nil {
+			closeBody(req)
+			return nil, err
+		}
+	}
+	if t.inFlight != nil {
+		if err := t.inFlight.Acquire(req.Context(), 1); err != nil {
+			closeBody(req)
+			return nil, err
+		}
+		defer t.inFlight.Release(1)
+	}
+	t.metrics.requests.Add(1)
+	t.metrics.inFlight.Add(1)
+	defer t.metrics.inFlight.Add(-1)
+	return t.base.RoundTrip(req)
+}
+
+// closeBody closes the body of a request which is not sent, a RoundTripper must close it even on errors
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
+
+func throttled(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+}
+
+// idempotent is true for the idempotent methods of RFC 9110 and requests with an Idempotency-Key header
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// rewind returns a copy of req with a fresh body to send it again, a request must not be modified by a RoundTripper
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, http.ErrBodyReadAfterClose
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Body = body
+	return req, nil
+}
+
+// retryAfter parses the Retry-After header of resp, seconds or an HTTP date, fallback if there is none
+func retryAfter(resp *http.Response, fallback time.Duration) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return fallback
+}
+
+// RetryAfter returns a *conrate.RetryAfterError for a response with status 429 or 503 so that an Executor task
+// requeues its param, nil for any other response
+func RetryAfter(resp *http.Response) error {
+	if !throttled(resp) {
+		return nil
+	}
+	return &conrate.RetryAfterError{After: retryAfter(resp, time.Second), Err: &StatusError{StatusCode: resp.StatusCode}}
+}
+
+// StatusError is the error of a response with an unexpected status
+type StatusError struct {
+	StatusCode int
+}
+
+func (s *StatusError) Error() string {
+	return strconv.Itoa(s.StatusCode) + " " + http.StatusText(s.StatusCode)
+}
+
+// NewTransport wraps base, http.DefaultTransport if nil
+func NewTransport(base http.RoundTripper, opts ...TransportOption) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{base: base, options: newTransportOptions(opts...), metrics: new(Metrics)}
+	if t.options.maxInFlight > 0 {
+		t.inFlight = semaphore.NewWeighted(int64(t.options.maxInFlight))
+	}
+	return t
+}
@@ -0,0 +1,260 @@
+package httplimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/riete/conrate"
+)
+
+// get sends n GET requests to url at once through client and returns their status codes
+func get(t *testing.T, client *http.Client, url string, n int) []int {
+	t.Helper()
+	codes := make([]int, n)
+	wg := new(sync.WaitGroup)
+	for i := range n {
+		wg.Go(func() {
+			resp, err := client.Get(url)
+			if err != nil {
+				t.Errorf("Get() = %v", err)
+				return
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			codes[i] = resp.StatusCode
+		})
+	}
+	wg.Wait()
+	return codes
+}
+
+// TestTransportLimiter expects requests beyond the burst of the limiter to wait for tokens.
+func TestTransportLimiter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+	transport := NewTransport(nil, WithLimiter(conrate.NewRateLimiter(20)))
+	client := &http.Client{Transport: transport}
+
+	start := time.Now()
+	get(t, client, server.URL, 30)
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("30 requests took %v at 20 qps, want >= 400ms", elapsed)
+	}
+	if got := transport.Metrics().Requests(); got != 30 {
+		t.Fatalf("Requests() = %d, want 30", got)
+	}
+}
+
+// TestTransportKeyedLimiter expects a limiter per host, a busy host not holding back another.
+func TestTransportKeyedLimiter(t *testing.T) {
+	handler := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	a, b := httptest.NewServer(handler), httptest.NewServer(handler)
+	defer a.Close()
+	defer b.Close()
+	keyed := conrate.NewKeyedLimiter(5)
+	client := &http.Client{Transport: NewTransport(nil, WithKeyedLimiter(keyed, nil))}
+
+	get(t, client, a.URL, 5)
+	start := time.Now()
+	get(t, client, b.URL, 5)
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Fatalf("5 requests to b took %v after a used its burst, want < 150ms", elapsed)
+	}
+	if got := keyed.Len(); got != 2 {
+		t.Fatalf("Len() = %d, want 2", got)
+	}
+}
+
+// TestTransportMaxInFlight expects at most WithMaxInFlight requests at the same time.
+func TestTransportMaxInFlight(t *testing.T) {
+	var inFlight, peak atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: NewTransport(nil, WithMaxInFlight(2))}
+
+	get(t, client, server.URL, 10)
+	if got := peak.Load(); got > 2 {
+		t.Fatalf("peak in-flight %d, want <= 2", got)
+	}
+}
+
+// throttling answers the first n requests with status and Retry-After, then 200 with the body of the request.
+func throttling(n int64, status int, retryAfter string) (*httptest.Server, *atomic.Int64) {
+	calls := new(atomic.Int64)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= n {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = io.Copy(w, r.Body)
+	})), calls
+}
+
+// TestTransportRetry expects:
+//   - idempotent requests answered with 429 or 503 to be sent again, with their body;
+//   - the limiter of the request to back off for Retry-After;
+//   - a POST without Idempotency-Key not to be retried;
+//   - the last response to be returned once retries are exhausted;
+//   - Throttled and Retries to count them.
+func TestTransportRetry(t *testing.T) {
+	t.Run("429", func(t *testing.T) {
+		server, calls := throttling(2, http.StatusTooManyRequests, "0")
+		defer server.Close()
+		limiter := conrate.NewRateLimiter(100)
+		transport := NewTransport(nil, WithLimiter(limiter))
+		client := &http.Client{Transport: transport}
+		if codes := get(t, client, server.URL, 1); codes[0] != http.StatusOK {
+			t.Fatalf("status %d, want 200", codes[0])
+		}
+		if calls.Load() != 3 || transport.Metrics().Retries() != 2 || transport.Metrics().Throttled() != 2 {
+			t.Fatalf("%d calls, %d retries, %d throttled, want 3, 2 and 2",
+				calls.Load(), transport.Metrics().Retries(), transport.Metrics().Throttled())
+		}
+	})
+	t.Run("503 with date and body", func(t *testing.T) {
+		server, calls := throttling(1, http.StatusServiceUnavailable, time.Now().Add(2*time.Second).UTC().Format(http.TimeFormat))
+		defer server.Close()
+		limiter := conrate.NewRateLimiter(100)
+		client := &http.Client{Transport: NewTransport(nil, WithLimiter(limiter))}
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+		req.Header.Set("Idempotency-Key", "1")
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Errorf("Do() = %v", err)
+				return
+			}
+			defer resp.Body.Close()
+			if body, _ := io.ReadAll(resp.Body); string(body) != "payload" {
+				t.Errorf("body %q, want payload", body)
+			}
+		}()
+		waitUntil(t, time.Second, func() bool { return calls.Load() == 1 })
+		time.Sleep(50 * time.Millisecond)
+		if !limiter.BackingOff() {
+			t.Error("expected the limiter backing off")
+		}
+		<-done
+		if got := calls.Load(); got != 2 {
+			t.Fatalf("%d calls, want 2", got)
+		}
+	})
+	t.Run("POST", func(t *testing.T) {
+		server, calls := throttling(1, http.StatusTooManyRequests, "0")
+		defer server.Close()
+		client := &http.Client{Transport: NewTransport(nil)}
+		resp, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+		if err != nil {
+			t.Fatalf("Post() = %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusTooManyRequests || calls.Load() != 1 {
+			t.Fatalf("status %d after %d calls, want 429 after 1", resp.StatusCode, calls.Load())
+		}
+	})
+	t.Run("exhausted", func(t *testing.T) {
+		server, calls := throttling(10, http.StatusTooManyRequests, "")
+		defer server.Close()
+		client := &http.Client{Transport: NewTransport(nil, WithRetries(2), WithRetryWait(10*time.Millisecond))}
+		if codes := get(t, client, server.URL, 1); codes[0] != http.StatusTooManyRequests || calls.Load() != 3 {
+			t.Fatalf("status %d after %d calls, want 429 after 3", codes[0], calls.Load())
+		}
+	})
+	t.Run("canceled", func(t *testing.T) {
+		server, _ := throttling(10, http.StatusTooManyRequests, "10")
+		defer server.Close()
+		transport := NewTransport(nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if _, err := transport.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("RoundTrip() = %v, want context.DeadlineExceeded", err)
+		}
+		if got := transport.Metrics().Errors(); got != 1 {
+			t.Fatalf("Errors() = %d, want 1", got)
+		}
+	})
+}
+
+// closeTracker is a request body which tells if it was closed
+type closeTracker struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+// TestTransportClosesBody expects the body of a request which is not sent to be closed:
+//   - when waiting for a token fails;
+//   - when the context is done while waiting to retry, the rewound body included.
+func TestTransportClosesBody(t *testing.T) {
+	limiter := conrate.NewRateLimiter(1)
+	limiter.Stop()
+	body := &closeTracker{Reader: strings.NewReader("payload")}
+	req, _ := http.NewRequest(http.MethodPut, "http://example.invalid", body)
+	if _, err := NewTransport(nil, WithLimiter(limiter)).RoundTrip(req); !errors.Is(err, conrate.ErrLimiterStopped) {
+		t.Fatalf("RoundTrip() = %v, want ErrLimiterStopped", err)
+	}
+	if !body.closed.Load() {
+		t.Fatal("expected the body closed")
+	}
+
+	server, _ := throttling(10, http.StatusTooManyRequests, "10")
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	rewound := &closeTracker{Reader: strings.NewReader("payload")}
+	req, _ = http.NewRequestWithContext(ctx, http.MethodPut, server.URL, strings.NewReader("payload"))
+	req.GetBody = func() (io.ReadCloser, error) { return rewound, nil }
+	if _, err := NewTransport(nil).RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RoundTrip() = %v, want context.DeadlineExceeded", err)
+	}
+	if !rewound.closed.Load() {
+		t.Fatal("expected the rewound body closed")
+	}
+}
+
+// TestRetryAfter expects a *conrate.RetryAfterError for 429 and 503 responses only.
+func TestRetryAfter(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"3"}}}
+	var retry *conrate.RetryAfterError
+	if err := RetryAfter(resp); !errors.As(err, &retry) || retry.After != 3*time.Second {
+		t.Fatalf("RetryAfter() = %v, want retry after 3s", err)
+	}
+	if err := RetryAfter(&http.Response{StatusCode: http.StatusOK}); err != nil {
+		t.Fatalf("RetryAfter() = %v, want nil", err)
+	}
+}
+
+func waitUntil(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
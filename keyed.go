@@ -0,0 +1,63 @@
+package conrate
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// KeyedLimiter holds a RateLimiter of the same capacity per key, e.g. per host or per client, created on first use.
+// A limiter whose bucket is full again is dropped since a new one behaves the same, unless it is paused, backing
+// off, stopped or pushing tokens to Wait.
+type KeyedLimiter struct {
+	capacity int
+	limiters map[string]*RateLimiter
+	swept    time.Time
+	mu       sync.Mutex
+}
+
+// Limiter returns the limiter of key
+func (k *KeyedLimiter) Limiter(key string) *RateLimiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if now := time.Now(); now.Sub(k.swept) >= time.Second {
+		k.swept = now
+		for key, limiter := range k.limiters {
+			if limiter.idle(now) {
+				delete(k.limiters, key)
+			}
+		}
+	}
+	limiter, ok := k.limiters[key]
+	if !ok {
+		limiter = NewRateLimiter(k.capacity)
+		k.limiters[key] = limiter
+	}
+	return limiter
+}
+
+// Take waits for a token of key, see RateLimiter.Take
+func (k *KeyedLimiter) Take(ctx context.Context, key string) error {
+	return k.Limiter(key).Take(ctx)
+}
+
+// TakeN waits for n tokens of key at once, see RateLimiter.TakeN
+func (k *KeyedLimiter) TakeN(ctx context.Context, key string, n int) error {
+	return k.Limiter(key).TakeN(ctx, n)
+}
+
+func (k *KeyedLimiter) Capacity() int {
+	return k.capacity
+}
+
+// Len is the number of limiters held
+func (k *KeyedLimiter) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.limiters)
+}
+
+// NewKeyedLimiter capacity is the maximum token rate of each key
+func NewKeyedLimiter(capacity int) *KeyedLimiter {
+	return &KeyedLimiter{capacity: capacity, limiters: make(map[string]*RateLimiter)}
+}
@@ -0,0 +1,36 @@
+package conrate
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestKeyedLimiter expects:
+//   - a limiter per key, the same one for the same key;
+//   - keys not to share tokens, an exhausted key not holding back another;
+//   - limiters with a full bucket to be dropped, one backing off to be kept.
+func TestKeyedLimiter(t *testing.T) {
+	k := NewKeyedLimiter(5)
+	if k.Limiter("a") != k.Limiter("a") || k.Limiter("a") == k.Limiter("b") {
+		t.Fatal("expected one limiter per key")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := k.TakeN(ctx, "a", 5); err != nil {
+		t.Fatalf("TakeN(a) = %v, want nil", err)
+	}
+	if err := k.TakeN(ctx, "b", 5); err != nil {
+		t.Fatalf("TakeN(b) = %v, want nil", err)
+	}
+	if err := k.Take(ctx, "a"); err == nil {
+		t.Fatal("Take(a) = nil, want an error with the bucket of a empty")
+	}
+
+	k.Limiter("c").Backoff(time.Hour)
+	time.Sleep(1100 * time.Millisecond)
+	k.Limiter("d")
+	if got := k.Len(); got != 2 {
+		t.Fatalf("Len() = %d, want 2, c backing off and d", got)
+	}
+}
@@ -0,0 +1,98 @@
+package conrate
+
+import "errors"
+
+var ErrExecutorNotStopped = errors.New("executor not stopped")
+
+type State int64
+
+const StateRunning State = 0
+const StatePaused State = 1
+
+// StateStopping GracefulStop or Shutdown is waiting for running tasks, no new tasks can be submitted
+const StateStopping State = 2
+const StateStopped State = 3
+
+func (s State) String() string {
+	switch s {
+	case StateRunning:
+		return "running"
+	case StatePaused:
+		return "paused"
+	case StateStopping:
+		return "stopping"
+	case StateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// lifecycle is what an executor recreates on every Restart, goroutines of a lifecycle only refer to their own
+type lifecycle[T any] struct {
+	limiter *RateLimiter
+	queue   *queue[T]
+	pool    *pool[T]
+	delayed *delayQueue[T]
+	// demand tells schedule a task may take a token, spare holds the tokens reclaimed from blocked or finished tasks
+	demand chan struct{}
+	spare  chan struct{}
+	stop   chan struct{}
+}
+
+// signal tells schedule a task may take a token
+func (lc *lifecycle[T]) signal() {
+	select {
+	case lc.demand <- struct{}{}:
+	default:
+	}
+}
+
+func (e *Executor[T]) newLifecycle(capacity, workers int) *lifecycle[T] {
+	o := e.options
+	lc := &lifecycle[T]{
+		limiter: NewRateLimiter(capacity),
+		queue:   newQueue[T](o.queueSize, o.queuePolicy, o.queueTimeout),
+		demand:  make(chan struct{}, 1),
+		spare:   make(chan struct{}, max(capacity, 1)),
+		stop:    make(chan struct{}),
+	}
+	lc.delayed = newDelayQueue(e.due, e.reject)
+	if workers > 0 {
+		lc.pool = newPool(workers, lc.stop, e.execute)
+	}
+	return lc
+}
+
+func (e *Executor[T]) current() *lifecycle[T] {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lc
+}
+
+func (e *Executor[T]) State() State {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state == StateRunning && e.lc.limiter.IsPaused() {
+		return StatePaused
+	}
+	return e.state
+}
+
+// Restart recreates the queue, limiter, workers and scheduler of a stopped executor with the same capacity,
+// counters are kept, ErrExecutorNotStopped if the executor is not in StateStopped
+func (e *Executor[T]) Restart() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state != StateStopped {
+		return ErrExecutorNotStopped
+	}
+	workers := 0
+	if e.lc.pool != nil {
+		workers = e.lc.pool.Size()
+	}
+	e.lc = e.newLifecycle(e.lc.limiter.Capacity(), workers)
+	e.state = StateRunning
+	go e.start(e.lc)
+	return nil
+}
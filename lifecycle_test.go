@@ -0,0 +1,113 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestRestart expects:
+//   - Restart after Stop to accept and run new tasks;
+//   - counters to be kept across Restart.
+func TestRestart(t *testing.T) {
+	p := NewRateLimitExecutor[int](100)
+	defer p.Stop()
+
+	builder := NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {})
+	if err := p.Submit(builder.BuildTask(ints(5))).WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	p.Stop()
+	if !errors.Is(p.Submit(builder.BuildTask(ints(1))).Error(), ErrExecutorStopped) {
+		t.Fatal("expected submit to fail after Stop()")
+	}
+
+	if err := p.Restart(); err != nil {
+		t.Fatalf("Restart() = %v, want nil", err)
+	}
+	if p.IsStopped() {
+		t.Fatal("expected executor not stopped after Restart()")
+	}
+	if err := p.Submit(builder.BuildTask(ints(7))).WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() after Restart() = %v, want nil", err)
+	}
+	if got := p.Counter().Completed(); got != 12 {
+		t.Fatalf("Completed() = %d, want 12", got)
+	}
+}
+
+// TestRestartNotStopped expects Restart of a running executor to fail with ErrExecutorNotStopped.
+func TestRestartNotStopped(t *testing.T) {
+	p := NewConcurrentExecutor[int](2)
+	defer p.Stop()
+
+	if err := p.Restart(); !errors.Is(err, ErrExecutorNotStopped) {
+		t.Fatalf("Restart() = %v, want ErrExecutorNotStopped", err)
+	}
+}
+
+// TestRestartKeepsWorkers expects the resized worker pool size to survive Restart.
+func TestRestartKeepsWorkers(t *testing.T) {
+	p := NewConcurrentExecutor[int](10, WithWorkers(2))
+	defer p.Stop()
+
+	p.SetWorkers(3)
+	p.GracefulStop(time.Second)
+	if err := p.Restart(); err != nil {
+		t.Fatalf("Restart() = %v, want nil", err)
+	}
+	if got := p.Workers(); got != 3 {
+		t.Fatalf("Workers() = %d, want 3", got)
+	}
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {}).BuildTask(ints(5)))
+	if err := f.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+}
+
+// TestState expects State to follow running -> paused -> running -> stopping -> stopped -> running.
+func TestState(t *testing.T) {
+	p := NewConcurrentExecutor[int](4)
+	defer p.Stop()
+
+	if got := p.State(); got != StateRunning {
+		t.Fatalf("State() = %v, want running", got)
+	}
+	p.Pause()
+	if got := p.State(); got != StatePaused {
+		t.Fatalf("State() = %v, want paused", got)
+	}
+	p.Resume()
+
+	release := make(chan struct{})
+	p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		<-release
+	}).BuildTask(ints(1)))
+	waitUntil(t, 3*time.Second, func() bool { return p.Counter().Running() == 1 })
+
+	done := make(chan struct{})
+	go func() {
+		p.GracefulStop(5 * time.Second)
+		close(done)
+	}()
+	waitUntil(t, 3*time.Second, func() bool { return p.State() == StateStopping })
+	if !p.IsStopped() {
+		t.Fatal("expected IsStopped() while stopping")
+	}
+	if err := p.Restart(); !errors.Is(err, ErrExecutorNotStopped) {
+		t.Fatalf("Restart() while stopping = %v, want ErrExecutorNotStopped", err)
+	}
+
+	close(release)
+	<-done
+	if got := p.State(); got != StateStopped {
+		t.Fatalf("State() = %v, want stopped", got)
+	}
+	if err := p.Restart(); err != nil {
+		t.Fatalf("Restart() = %v, want nil", err)
+	}
+	if got := p.State(); got != StateRunning {
+		t.Fatalf("State() = %v, want running", got)
+	}
+}
@@ -1,21 +1,35 @@
 package conrate
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"golang.org/x/time/rate"
 )
 
+var ErrLimiterStopped = errors.New("rate limiter stopped")
+
+// errTakeDone is returned by take when its done channel is closed
+var errTakeDone = errors.New("take done")
+
+// RateLimiter hands out tokens at capacity per second with a burst of capacity, either on demand with Take and TakeN
+// or pushed to the Wait channel once it is used
 type RateLimiter struct {
 	limiter  *rate.Limiter
 	wait     chan struct{}
+	pushing  bool
 	stop     chan struct{}
 	stopped  bool
 	paused   bool
 	capacity int
-	wg       sync.WaitGroup
-	mu       sync.Mutex
+	// backoff is when the current Backoff ends, changed is closed on every capacity change
+	backoff time.Time
+	changed chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
 }
 
 func (r *RateLimiter) Capacity() int {
@@ -27,17 +41,68 @@ func (r *RateLimiter) Capacity() int {
 func (r *RateLimiter) setCapacity(capacity int) {
 	r.limiter.SetLimit(rate.Limit(capacity))
 	r.limiter.SetBurst(capacity)
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// held is true while paused or backing off, r.mu must be held
+func (r *RateLimiter) held() bool {
+	return r.paused || time.Now().Before(r.backoff)
 }
 
 func (r *RateLimiter) SetCapacity(capacity int) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.capacity = capacity
-	if !r.paused {
+	if !r.held() {
 		r.setCapacity(capacity)
 	}
 }
 
+// Backoff dispatches no token for d and drops tokens not taken yet, a longer Backoff extends the current one.
+// Tokens dispatch again once both the backoff ended and the limiter is resumed.
+func (r *RateLimiter) Backoff(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	until := time.Now().Add(d)
+	if r.stopped || !until.After(r.backoff) {
+		return
+	}
+	r.backoff = until
+	r.setCapacity(0)
+	for len(r.wait) > 0 {
+		<-r.wait
+	}
+	time.AfterFunc(d, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if !r.stopped && !r.held() {
+			r.setCapacity(r.capacity)
+		}
+	})
+}
+
+// idle is true if the bucket is full and the limiter neither holds tokens back nor pushes them, it is as good as new
+func (r *RateLimiter) idle(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.stopped && !r.pushing && !r.held() && r.limiter.TokensAt(now) >= float64(r.capacity)
+}
+
+// holding is true while paused, backing off or stopped
+func (r *RateLimiter) holding() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stopped || r.held()
+}
+
+// BackingOff is true until the current Backoff ends
+func (r *RateLimiter) BackingOff() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return time.Now().Before(r.backoff)
+}
+
 func (r *RateLimiter) Pause() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -51,55 +116,130 @@ func (r *RateLimiter) Resume() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if !r.stopped && r.paused {
-		r.setCapacity(r.capacity)
 		r.paused = false
+		if !r.held() {
+			r.setCapacity(r.capacity)
+		}
 	}
 }
 
-func (r *RateLimiter) dispatch() {
+// take waits for n tokens of the bucket, it fails with ErrLimiterStopped, or errTakeDone once done or abort is closed
+// or abort receives
+func (r *RateLimiter) take(n int, done, abort <-chan struct{}) error {
 	for {
+		r.mu.Lock()
+		changed := r.changed
+		r.mu.Unlock()
 		select {
 		case <-r.stop:
-			return
+			return ErrLimiterStopped
 		default:
 		}
+		reservation := r.limiter.ReserveN(time.Now(), n)
+		var timer *time.Timer
+		var ready <-chan time.Time
+		if reservation.OK() {
+			timer = time.NewTimer(reservation.Delay())
+			ready = timer.C
+		}
+		// a reservation which is not ok waits for the capacity to change: paused, backing off or n above the burst
+		var err error
+		retry := false
+		select {
+		case <-ready:
+		case <-changed:
+			retry = true
+		case <-r.stop:
+			err = ErrLimiterStopped
+		case <-done:
+			err = errTakeDone
+		case <-abort:
+			err = errTakeDone
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if retry || err != nil {
+			reservation.Cancel()
+		}
+		if !retry {
+			return err
+		}
+	}
+}
+
+// tryTake takes n tokens of the bucket if there are, without waiting
+func (r *RateLimiter) tryTake(n int) bool {
+	return r.limiter.AllowN(time.Now(), n)
+}
+
+// Take waits for a token, it fails with ErrLimiterStopped or with the error of ctx
+func (r *RateLimiter) Take(ctx context.Context) error {
+	return r.TakeN(ctx, 1)
+}
+
+// TakeN waits for n tokens at once, it fails with ErrCostExceedsCapacity if n is more than the capacity, with
+// ErrLimiterStopped or with the error of ctx
+func (r *RateLimiter) TakeN(ctx context.Context, n int) error {
+	if capacity := r.Capacity(); n > capacity {
+		return fmt.Errorf("%w: cost %d, capacity %d", ErrCostExceedsCapacity, n, capacity)
+	}
+	if err := r.take(n, ctx.Done(), nil); err != errTakeDone {
+		return err
+	}
+	return ctx.Err()
+}
 
-		if r.limiter.Burst() == 0 {
-			select {
-			case <-r.stop:
-				return
-			case <-time.After(time.Second):
-				continue
-			}
+// TryTake takes a token if there is one now, else it tells how long until there is one without taking it
+func (r *RateLimiter) TryTake() (ok bool, wait time.Duration) {
+	now := time.Now()
+	reservation := r.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		// paused, backing off or stopped
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return false, max(r.backoff.Sub(now), time.Second)
+	}
+	if wait = reservation.DelayFrom(now); wait > 0 {
+		reservation.CancelAt(now)
+		return false, wait
+	}
+	return true, 0
+}
+
+// Available is the number of tokens which can be taken now
+func (r *RateLimiter) Available() int {
+	return max(int(r.limiter.Tokens()), 0)
+}
+
+// dispatch pushes tokens to the Wait channel
+func (r *RateLimiter) dispatch() {
+	for {
+		if r.take(1, nil, nil) != nil {
+			return
 		}
-		reserver := r.limiter.Reserve()
 		select {
 		case <-r.stop:
-			reserver.Cancel()
 			return
-		case <-time.After(reserver.Delay()):
-			select {
-			case <-r.stop:
-				reserver.Cancel()
-				return
-			case r.wait <- struct{}{}:
-			}
+		case r.wait <- struct{}{}:
 		}
 	}
 }
 
 func (r *RateLimiter) Stop() {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-	if !r.stopped {
-		r.setCapacity(0)
-		close(r.stop)
-		r.stopped = true
-		r.paused = true
-		r.wg.Wait()
-		for len(r.wait) > 0 {
-			<-r.wait
-		}
+	if r.stopped {
+		r.mu.Unlock()
+		return
+	}
+	r.setCapacity(0)
+	close(r.stop)
+	r.stopped = true
+	r.paused = true
+	r.mu.Unlock()
+	r.wg.Wait()
+	for len(r.wait) > 0 {
+		<-r.wait
 	}
 }
 
@@ -119,7 +259,15 @@ func (r *RateLimiter) IsPaused() bool {
 	return r.paused
 }
 
+// Wait returns the channel tokens are pushed to, tokens are pushed from the first call on and compete with
+// Take and TakeN
 func (r *RateLimiter) Wait() chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.pushing && !r.stopped {
+		r.pushing = true
+		r.wg.Go(r.dispatch)
+	}
 	return r.wait
 }
 
@@ -129,10 +277,8 @@ func NewRateLimiter(capacity int) *RateLimiter {
 		limiter:  rate.NewLimiter(rate.Limit(capacity), capacity),
 		stop:     make(chan struct{}),
 		wait:     make(chan struct{}, 64),
+		changed:  make(chan struct{}),
 		capacity: capacity,
 	}
-	limiter.wg.Go(func() {
-		limiter.dispatch()
-	})
 	return limiter
 }
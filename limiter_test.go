@@ -1,6 +1,8 @@
 package conrate
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -137,3 +139,98 @@ func TestRateLimiterResumeAfterStopNoOp(t *testing.T) {
 		t.Fatal("expected still stopped after Resume()")
 	}
 }
+
+// TestRateLimiterBackoff expects:
+//   - no token during Backoff and tokens again once it ended;
+//   - a limiter paused during Backoff to stay paused after it ended until Resume.
+func TestRateLimiterBackoff(t *testing.T) {
+	l := NewRateLimiter(100)
+	defer l.Stop()
+
+	l.Backoff(200 * time.Millisecond)
+	if !l.BackingOff() {
+		t.Fatal("expected backing off after Backoff()")
+	}
+	select {
+	case <-l.Wait():
+		t.Fatal("received token while backing off")
+	case <-time.After(150 * time.Millisecond):
+	}
+	select {
+	case <-l.Wait():
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for token after Backoff()")
+	}
+
+	l.Backoff(100 * time.Millisecond)
+	l.Pause()
+	time.Sleep(150 * time.Millisecond)
+	if l.BackingOff() {
+		t.Fatal("expected backoff ended")
+	}
+	for len(l.Wait()) > 0 {
+		<-l.Wait()
+	}
+	select {
+	case <-l.Wait():
+		t.Fatal("received token while paused after Backoff()")
+	case <-time.After(200 * time.Millisecond):
+	}
+	l.Resume()
+	select {
+	case <-l.Wait():
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for token after Resume()")
+	}
+}
+
+// TestRateLimiterTakeN expects:
+//   - TakeN to fail with ErrCostExceedsCapacity above the capacity;
+//   - TakeN to take n tokens, failing with the ctx error or ErrLimiterStopped.
+func TestRateLimiterTakeN(t *testing.T) {
+	l := NewRateLimiter(10)
+
+	if err := l.TakeN(context.Background(), 11); !errors.Is(err, ErrCostExceedsCapacity) {
+		t.Fatalf("TakeN(11) = %v, want ErrCostExceedsCapacity", err)
+	}
+	if err := l.TakeN(context.Background(), 10); err != nil {
+		t.Fatalf("TakeN(10) = %v, want nil", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	l.Pause()
+	for len(l.Wait()) > 0 {
+		<-l.Wait()
+	}
+	if err := l.TakeN(ctx, 5); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("TakeN(5) = %v, want context.DeadlineExceeded", err)
+	}
+	l.Stop()
+	if err := l.TakeN(context.Background(), 1); !errors.Is(err, ErrLimiterStopped) {
+		t.Fatalf("TakeN(1) = %v, want ErrLimiterStopped", err)
+	}
+}
+
+// TestRateLimiterTryTake expects:
+//   - TryTake to take the tokens of the burst and then tell the wait for the next one;
+//   - Available to count the tokens left;
+//   - TryTake to tell the rest of a Backoff.
+func TestRateLimiterTryTake(t *testing.T) {
+	l := NewRateLimiter(10)
+	defer l.Stop()
+	for range 10 {
+		if ok, _ := l.TryTake(); !ok {
+			t.Fatal("TryTake() = false within the burst")
+		}
+	}
+	if got := l.Available(); got != 0 {
+		t.Fatalf("Available() = %d, want 0", got)
+	}
+	if ok, wait := l.TryTake(); ok || wait <= 0 || wait > 100*time.Millisecond {
+		t.Fatalf("TryTake() = %v, %v, want false and at most 100ms", ok, wait)
+	}
+	l.Backoff(time.Minute)
+	if ok, wait := l.TryTake(); ok || wait < 59*time.Second {
+		t.Fatalf("TryTake() = %v, %v, want false and about a minute", ok, wait)
+	}
+}
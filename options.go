@@ -0,0 +1,128 @@
+package conrate
+
+import "time"
+
+type options struct {
+	queueSize    int
+	queuePolicy  QueuePolicy
+	queueTimeout time.Duration
+	workers      int
+	drain        bool
+	store        Store
+	codec        any
+	dedupTTL     time.Duration
+	breaker      *BreakerConfig
+	scheduling   SchedulingPolicy
+	scheduler    any
+	tenants      map[string]int
+	maxRequeues  int
+}
+
+type Option func(*options)
+
+// WithQueueSize bounds the number of submitted tasks not finished yet, queued or running, size <= 0 means unbounded
+// which is the default
+func WithQueueSize(size int) Option {
+	return func(o *options) {
+		o.queueSize = size
+	}
+}
+
+// WithQueuePolicy decides what submit does when the queue is full, default is QueueBlock
+func WithQueuePolicy(policy QueuePolicy) Option {
+	return func(o *options) {
+		o.queuePolicy = policy
+	}
+}
+
+// WithQueueTimeout is the maximum time a QueueBlock submit waits for room, timeout <= 0 waits forever
+func WithQueueTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.queueTimeout = timeout
+	}
+}
+
+// WithWorkers runs params in a fixed and resizable set of long-lived workers instead of a goroutine per param,
+// it bounds the goroutines parked in task functions, workers <= 0 means a goroutine per param which is the default
+func WithWorkers(workers int) Option {
+	return func(o *options) {
+		o.workers = workers
+	}
+}
+
+// WithMaxRequeues is how many times a param failing with *RetryAfterError is requeued, it fails with the error once
+// requeued n times, n < 0 requeues it until the task stops, default is 10
+func WithMaxRequeues(n int) Option {
+	return func(o *options) {
+		o.maxRequeues = n
+	}
+}
+
+// WithDrainOnShutdown makes Executor.Shutdown run queued params which have not started yet instead of canceling them
+func WithDrainOnShutdown(drain bool) Option {
+	return func(o *options) {
+		o.drain = drain
+	}
+}
+
+// WithStore persists submitted tasks and the progress of their params, see Executor.ResumeFrom
+func WithStore(store Store) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+// WithCodec encodes params for the Store, default is JSONCodec, the codec must encode the param type of the executor
+func WithCodec[T any](codec Codec[T]) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+// WithDedupCache keeps the dedup keys of succeeded params for ttl, params with a cached key complete without running,
+// see TaskBuilder.WithDedupKey
+func WithDedupCache(ttl time.Duration) Option {
+	return func(o *options) {
+		o.dedupTTL = ttl
+	}
+}
+
+// WithCircuitBreaker guards task functions with a circuit breaker driven by the outcomes of params, one for the
+// executor or one per key of tasks built with TaskBuilder.WithBreakerKey
+func WithCircuitBreaker(config BreakerConfig) Option {
+	return func(o *options) {
+		o.breaker = &config
+	}
+}
+
+// WithSchedulingPolicy decides which task of a tenant gets the next token of the tenant, default is SchedulingSWRR
+func WithSchedulingPolicy(policy SchedulingPolicy) Option {
+	return func(o *options) {
+		o.scheduling = policy
+	}
+}
+
+// WithTenantWeights sets the share of executor tokens of tenants, see TaskBuilder.WithTenant, a tenant missing from
+// weights weighs 1. It has no effect with WithScheduler.
+func WithTenantWeights(weights map[string]int) Option {
+	return func(o *options) {
+		o.tenants = weights
+	}
+}
+
+// WithScheduler decides which task gets the next executor token with a custom Scheduler instead of a
+// SchedulingPolicy per tenant, NewTenantScheduler shares tokens between tenants. The scheduler belongs to the
+// executor and T must be its param type, NewExecutor panics otherwise.
+func WithScheduler[T any](scheduler Scheduler[T]) Option {
+	return func(o *options) {
+		o.scheduler = scheduler
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{queuePolicy: QueueBlock, maxRequeues: 10}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
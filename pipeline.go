@@ -0,0 +1,173 @@
+package conrate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Stage configures the executor of one Pipeline stage
+type Stage struct {
+	Name     string
+	Mode     ExecutorMode
+	Capacity int
+	// Buffer is the size of the stage output buffer, Capacity if <= 0
+	Buffer  int
+	Options []Option
+}
+
+// StageError is the error of a Pipeline whose stage Stage failed
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (s *StageError) Error() string {
+	return "stage " + s.Stage + ": " + s.Err.Error()
+}
+
+func (s *StageError) Unwrap() error {
+	return s.Err
+}
+
+// pipelineRun is shared by every stage of a Pipeline
+type pipelineRun struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	err     error
+	futures []*Future
+	mu      sync.Mutex
+}
+
+// fail keeps the first failure and cancels every stage
+func (r *pipelineRun) fail(err error) {
+	r.mu.Lock()
+	if r.err == nil {
+		r.err = err
+	}
+	r.mu.Unlock()
+	r.cancel(err)
+}
+
+// Pipeline is a chain of stages, each running on its own executor, the output of a stage streams into the next one.
+// A stage holds at most Stage.Capacity items in flight and Stage.Buffer items in its output, a slow stage blocks
+// the stages before it. The first failed item cancels the whole pipeline.
+type Pipeline[T any] struct {
+	run *pipelineRun
+	out <-chan T
+}
+
+// Output streams the output of the last stage, it is closed once every stage is done and must be drained
+func (p *Pipeline[T]) Output() <-chan T {
+	return p.out
+}
+
+// Future is done once every stage added so far is done, it fails with the *StageError of the first failed item
+// or with the cause of the pipeline context
+func (p *Pipeline[T]) Future() *Future {
+	p.run.mu.Lock()
+	futures := p.run.futures
+	p.run.mu.Unlock()
+	f := newFuture(p.Cancel)
+	f.tasks = tasksOf(futures)
+	go func() {
+		for _, future := range futures {
+			future.Wait()
+		}
+		p.run.mu.Lock()
+		err := p.run.err
+		p.run.mu.Unlock()
+		if err == nil {
+			err = context.Cause(p.run.ctx)
+		}
+		f.resolve(err)
+	}()
+	return f
+}
+
+// Cancel stops every stage
+func (p *Pipeline[T]) Cancel() {
+	p.run.cancel(context.Canceled)
+}
+
+// NewPipeline starts a pipeline reading items from source until it is closed or ctx is done
+func NewPipeline[T any](ctx context.Context, source <-chan T) *Pipeline[T] {
+	run := &pipelineRun{}
+	run.ctx, run.cancel = context.WithCancelCause(ctx)
+	return &Pipeline[T]{run: run, out: source}
+}
+
+// Then appends a stage running f on each output item of p and returns the pipeline of its outputs,
+// an item failing with an error or a panic fails the pipeline
+func Then[In, Out any](p *Pipeline[In], stage Stage, f func(context.Context, In) (Out, error)) *Pipeline[Out] {
+	run := p.run
+	capacity := max(stage.Capacity, 1)
+	buffer := stage.Buffer
+	if buffer <= 0 {
+		buffer = capacity
+	}
+	in := make(chan In)
+	out := make(chan Out, buffer)
+	// slots bounds the items in flight, a slot is taken before reading an item and freed once its output is sent
+	slots := make(chan struct{}, capacity)
+	go func() {
+		defer close(in)
+		for {
+			select {
+			case <-run.ctx.Done():
+				return
+			case slots <- struct{}{}:
+			}
+			select {
+			case <-run.ctx.Done():
+				return
+			case item, ok := <-p.out:
+				if !ok {
+					return
+				}
+				select {
+				case <-run.ctx.Done():
+					return
+				case in <- item:
+				}
+			}
+		}
+	}()
+
+	executor := NewExecutor[In](capacity, stage.Mode, stage.Options...)
+	task := NewTaskBuilder[In]().
+		WithName(stage.Name).
+		WithContext(run.ctx).
+		WithTaskFuncE(func(ctx context.Context, item In) (err error) {
+			defer func() { <-slots }()
+			// a panic fails the pipeline like an error instead of dropping the item
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("%w: %v", ErrTaskPanic, r)
+					run.fail(&StageError{Stage: stage.Name, Err: err})
+				}
+			}()
+			result, err := f(ctx, item)
+			if err != nil {
+				run.fail(&StageError{Stage: stage.Name, Err: err})
+				return err
+			}
+			select {
+			case out <- result:
+				return nil
+			case <-ctx.Done():
+				return context.Cause(ctx)
+			}
+		}).
+		BuildStreamTask(in)
+	future := executor.Submit(task)
+	run.mu.Lock()
+	run.futures = append(run.futures, future)
+	run.mu.Unlock()
+	go func() {
+		future.Wait()
+		executor.Stop()
+		close(out)
+	}()
+	return &Pipeline[Out]{run: run, out: out}
+}
@@ -0,0 +1,142 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// generate sends 0..n-1 to the returned channel, counting the items read, until n is reached or ctx is done.
+func generate(ctx context.Context, n int, read *atomic.Int64) <-chan int {
+	source := make(chan int)
+	go func() {
+		defer close(source)
+		for i := range n {
+			select {
+			case <-ctx.Done():
+				return
+			case source <- i:
+				read.Add(1)
+			}
+		}
+	}()
+	return source
+}
+
+// TestPipeline expects every item to flow through a rate limited stage and a concurrent stage into Output.
+func TestPipeline(t *testing.T) {
+	const n = 20
+	read := new(atomic.Int64)
+	p := NewPipeline(context.Background(), generate(context.Background(), n, read))
+	doubled := Then(p, Stage{Name: "double", Mode: RateLimitMode, Capacity: 100}, func(_ context.Context, i int) (int, error) {
+		return i * 2, nil
+	})
+	formatted := Then(doubled, Stage{Name: "format", Mode: ConcurrencyMode, Capacity: 50}, func(_ context.Context, i int) (string, error) {
+		return strconv.Itoa(i), nil
+	})
+	f := formatted.Future()
+
+	var got []string
+	for s := range formatted.Output() {
+		got = append(got, s)
+	}
+	if err := f.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	slices.Sort(got)
+	want := make([]string, 0, n)
+	for i := range n {
+		want = append(want, strconv.Itoa(i*2))
+	}
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("Output() = %v, want %v", got, want)
+	}
+	if got := f.Counter().Completed(); got != 2*n {
+		t.Fatalf("Completed() = %d, want %d", got, 2*n)
+	}
+}
+
+// TestPipelineBackpressure expects a stage whose output is not drained to stop reading its input
+// after Capacity items in flight and Buffer items buffered.
+func TestPipelineBackpressure(t *testing.T) {
+	read := new(atomic.Int64)
+	p := Then(NewPipeline(context.Background(), generate(context.Background(), 1000, read)),
+		Stage{Name: "fast", Mode: RateLimitMode, Capacity: 10, Buffer: 1},
+		func(_ context.Context, i int) (int, error) {
+			return i, nil
+		})
+	p = Then(p, Stage{Name: "stuck", Mode: RateLimitMode, Capacity: 1, Buffer: 1}, func(ctx context.Context, i int) (int, error) {
+		<-ctx.Done()
+		return i, nil
+	})
+
+	// fast alone would read about 20 items in 2s
+	time.Sleep(2 * time.Second)
+	// 10 in flight and 1 buffered in fast, 1 in flight in stuck and 1 taken by each feeder
+	if got := read.Load(); got > 14 {
+		t.Fatalf("%d items read from the source, want <= 14", got)
+	}
+	p.Cancel()
+	for range p.Output() {
+	}
+	if err := p.Future().WaitTimeout(5 * time.Second); !errors.Is(err, context.Canceled) {
+		t.Fatalf("WaitTimeout() = %v, want context.Canceled", err)
+	}
+}
+
+// TestPipelineError expects an item failing in a later stage to cancel every stage and fail Future with *StageError.
+func TestPipelineError(t *testing.T) {
+	read := new(atomic.Int64)
+	errBad := errors.New("bad item")
+	p := Then(NewPipeline(context.Background(), generate(context.Background(), 1000, read)),
+		Stage{Name: "pass", Mode: RateLimitMode, Capacity: 100},
+		func(_ context.Context, i int) (int, error) {
+			return i, nil
+		})
+	p = Then(p, Stage{Name: "check", Mode: RateLimitMode, Capacity: 100}, func(_ context.Context, i int) (int, error) {
+		if i == 5 {
+			return 0, errBad
+		}
+		return i, nil
+	})
+	f := p.Future()
+	for range p.Output() {
+	}
+
+	err := f.WaitTimeout(5 * time.Second)
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) || stageErr.Stage != "check" || !errors.Is(err, errBad) {
+		t.Fatalf("WaitTimeout() = %v, want *StageError of stage check wrapping errBad", err)
+	}
+	if got := read.Load(); got == 1000 {
+		t.Fatal("expected the source not to be drained after the failure")
+	}
+}
+
+// TestPipelinePanic expects an item panicking in a stage to fail Future with *StageError wrapping ErrTaskPanic.
+func TestPipelinePanic(t *testing.T) {
+	source := make(chan int, 5)
+	for i := range 5 {
+		source <- i
+	}
+	close(source)
+	p := Then(NewPipeline(context.Background(), source), Stage{Name: "explode", Capacity: 1}, func(_ context.Context, i int) (int, error) {
+		if i == 2 {
+			panic("boom")
+		}
+		return i, nil
+	})
+	f := p.Future()
+	for range p.Output() {
+	}
+	err := f.WaitTimeout(5 * time.Second)
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) || stageErr.Stage != "explode" || !errors.Is(err, ErrTaskPanic) {
+		t.Fatalf("WaitTimeout() = %v, want *StageError of stage explode wrapping ErrTaskPanic", err)
+	}
+}
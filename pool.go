@@ -0,0 +1,96 @@
+package conrate
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// job is one param or one batch of params handed over to a pool worker, it is passed by value to avoid a per param
+// allocation, index is the index of the first param
+type job[T any] struct {
+	task     *Task[T]
+	index    int
+	params   []T
+	cost     int
+	canceled *atomic.Int64
+	idle     *semaphore.Weighted
+	wg       *sync.WaitGroup
+	// acquire takes the limits of the task again for a requeued job, requeues counts the times it was requeued
+	acquire  func([]T) (int, bool)
+	requeues int
+}
+
+// pool is a fixed and resizable set of long-lived workers running params
+type pool[T any] struct {
+	jobs    chan job[T]
+	quit    chan struct{}
+	stop    chan struct{}
+	size    int
+	handler func(job[T])
+	mu      sync.Mutex
+}
+
+func (p *pool[T]) work() {
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-p.quit:
+			return
+		case j := <-p.jobs:
+			p.handler(j)
+			j.wg.Done()
+		}
+	}
+}
+
+// submit blocks until a worker takes j, false if the pool is stopped first
+func (p *pool[T]) submit(j job[T]) bool {
+	select {
+	case <-p.stop:
+		return false
+	case p.jobs <- j:
+		return true
+	}
+}
+
+func (p *pool[T]) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+// resize starts new workers or asks idle workers to quit after their current param
+func (p *pool[T]) resize(size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	size = max(size, 1)
+	for range size - p.size {
+		go p.work()
+	}
+	if n := p.size - size; n > 0 {
+		go func() {
+			for range n {
+				select {
+				case <-p.stop:
+					return
+				case p.quit <- struct{}{}:
+				}
+			}
+		}()
+	}
+	p.size = size
+}
+
+func newPool[T any](size int, stop chan struct{}, handler func(job[T])) *pool[T] {
+	p := &pool[T]{
+		jobs:    make(chan job[T]),
+		quit:    make(chan struct{}),
+		stop:    stop,
+		handler: handler,
+	}
+	p.resize(size)
+	return p
+}
@@ -0,0 +1,158 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWorkerPoolCompletesAll expects:
+//   - WithWorkers(3): at most 3 params running at once even if executor capacity is larger;
+//   - Completed()==n after all finish.
+func TestWorkerPoolCompletesAll(t *testing.T) {
+	p := NewRateLimitExecutor[int](200, WithWorkers(3))
+	defer p.Stop()
+
+	var running, maxRunning atomic.Int64
+	const n = 30
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		r := running.Add(1)
+		for {
+			m := maxRunning.Load()
+			if r <= m || maxRunning.CompareAndSwap(m, r) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+	}).BuildTask(ints(n)))
+	if err := f.WaitTimeout(10 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+
+	if got := maxRunning.Load(); got > 3 {
+		t.Fatalf("max running %d, want <= 3", got)
+	}
+	assertCounterZeroPending(t, p.Counter())
+	if got := p.Counter().Completed(); got != n {
+		t.Fatalf("Completed() = %d, want %d", got, n)
+	}
+}
+
+// TestWorkerPoolResize expects:
+//   - SetWorkers to grow the pool so more params run at once;
+//   - SetWorkers to be a no-op without WithWorkers.
+func TestWorkerPoolResize(t *testing.T) {
+	p := NewConcurrentExecutor[int](10, WithWorkers(1))
+	defer p.Stop()
+
+	release := make(chan struct{})
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		<-release
+	}).BuildTask(ints(6)))
+
+	waitUntil(t, 3*time.Second, func() bool { return p.Counter().Running() == 1 })
+	p.SetWorkers(4)
+	if got := p.Workers(); got != 4 {
+		t.Fatalf("Workers() = %d, want 4", got)
+	}
+	waitUntil(t, 3*time.Second, func() bool { return p.Counter().Running() == 4 })
+
+	p.SetWorkers(2)
+	close(release)
+	f.Wait()
+	assertCounterZeroPending(t, p.Counter())
+
+	plain := NewConcurrentExecutor[int](2)
+	defer plain.Stop()
+	plain.SetWorkers(4)
+	if got := plain.Workers(); got != 0 {
+		t.Fatalf("Workers() = %d, want 0", got)
+	}
+}
+
+// TestWorkerPoolStop expects params waiting for a worker to be canceled on Stop with ErrExecutorStopped.
+func TestWorkerPoolStop(t *testing.T) {
+	p := NewRateLimitExecutor[int](100, WithWorkers(1))
+
+	release := make(chan struct{})
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		<-release
+	}).BuildTask(ints(5)))
+
+	waitUntil(t, 3*time.Second, func() bool { return p.Counter().Running() == 1 })
+	p.Stop()
+	close(release)
+	f.Wait()
+
+	if !errors.Is(f.Error(), ErrExecutorStopped) {
+		t.Fatalf("Error() = %v, want ErrExecutorStopped", f.Error())
+	}
+	assertCounterZeroPending(t, p.Counter())
+	if got := p.Counter().Completed() + p.Counter().Canceled(); got != 5 {
+		t.Fatalf("completed + canceled = %d, want 5", got)
+	}
+}
+
+// peakGoroutines samples runtime.NumGoroutine until stop is closed.
+func peakGoroutines(stop chan struct{}) *atomic.Int64 {
+	peak := new(atomic.Int64)
+	go func() {
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if n := int64(runtime.NumGoroutine()); n > peak.Load() {
+					peak.Store(n)
+				}
+			}
+		}
+	}()
+	return peak
+}
+
+// benchmarkDispatch hands 1M params of a slow task function over to Executor.spawn, bypassing rate limiting,
+// and reports the peak number of goroutines.
+func benchmarkDispatch(b *testing.B, opts ...Option) {
+	const n = 1 << 20
+	p := NewRateLimitExecutor[int](1, opts...)
+	defer p.Stop()
+	task := NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		time.Sleep(100 * time.Microsecond)
+	}).BuildTask(ints(n))
+	task.counter = newCounter(p.counter)
+	task.lc = p.current()
+
+	stop := make(chan struct{})
+	peak := peakGoroutines(stop)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		wg := new(sync.WaitGroup)
+		canceled := new(atomic.Int64)
+		canceled.Store(n)
+		for i := range n {
+			p.spawn(job[int]{task: task, index: i, params: task.param[i : i+1], cost: 1, canceled: canceled, wg: wg})
+		}
+		wg.Wait()
+	}
+	b.StopTimer()
+	close(stop)
+	b.ReportMetric(float64(peak.Load()), "peak-goroutines")
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/param")
+}
+
+func BenchmarkDispatchGoroutinePerParam(b *testing.B) {
+	benchmarkDispatch(b)
+}
+
+func BenchmarkDispatchWorkerPool(b *testing.B) {
+	benchmarkDispatch(b, WithWorkers(runtime.GOMAXPROCS(0)*64))
+}
@@ -0,0 +1,152 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrQueueFull = errors.New("queue full")
+var ErrTaskDropped = errors.New("task dropped")
+
+type QueuePolicy int64
+
+// QueueBlock submit blocks until the queue has room, the queue timeout elapses or the submit context is done
+const QueueBlock QueuePolicy = 0
+
+// QueueReject submit fails immediately if the queue is full
+const QueueReject QueuePolicy = 1
+
+// QueueDropOldest submit drops the oldest queued task to make room if the queue is full
+const QueueDropOldest QueuePolicy = 2
+
+// RejectedError is returned through Future.Error when a task is not accepted or dropped by the queue,
+// Err is ErrQueueFull, ErrTaskDropped or the context error of a blocked submit
+type RejectedError struct {
+	Policy QueuePolicy
+	Err    error
+}
+
+func (r *RejectedError) Error() string {
+	return "task rejected: " + r.Err.Error()
+}
+
+func (r *RejectedError) Unwrap() error {
+	return r.Err
+}
+
+// queue is the FIFO of submitted tasks waiting to be dispatched, size bounds the tasks accepted and not finished yet:
+// a task keeps its room from push until release once it finished
+type queue[T any] struct {
+	tasks   []*Task[T]
+	active  int
+	size    int
+	policy  QueuePolicy
+	timeout time.Duration
+	closed  bool
+	ready   chan struct{}
+	freed   chan struct{}
+	mu      sync.Mutex
+}
+
+// push adds task to the queue, dropped is the task evicted by QueueDropOldest which gives its room to task, block
+// false never waits for room. Only tasks not dispatched yet can be evicted.
+func (q *queue[T]) push(ctx context.Context, task *Task[T], block bool) (dropped *Task[T], err error) {
+	var timeout <-chan time.Time
+	if q.timeout > 0 {
+		timer := time.NewTimer(q.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return nil, ErrExecutorStopped
+		}
+		if q.size > 0 && q.active >= q.size {
+			switch {
+			case q.policy == QueueDropOldest && len(q.tasks) > 0:
+				dropped = q.tasks[0]
+				q.tasks[0] = nil
+				q.tasks = q.tasks[1:]
+				q.active--
+			case q.policy == QueueReject || !block:
+				q.mu.Unlock()
+				return nil, &RejectedError{Policy: q.policy, Err: ErrQueueFull}
+			default:
+				freed := q.freed
+				q.mu.Unlock()
+				select {
+				case <-freed:
+					continue
+				case <-timeout:
+					return nil, &RejectedError{Policy: q.policy, Err: ErrQueueFull}
+				case <-ctx.Done():
+					return nil, &RejectedError{Policy: q.policy, Err: ctx.Err()}
+				}
+			}
+		}
+		q.tasks = append(q.tasks, task)
+		q.active++
+		select {
+		case q.ready <- struct{}{}:
+		default:
+		}
+		q.mu.Unlock()
+		return dropped, nil
+	}
+}
+
+// pop waits for the oldest task, ok is false once the queue is closed and drained
+func (q *queue[T]) pop() (task *Task[T], ok bool) {
+	for {
+		q.mu.Lock()
+		if len(q.tasks) > 0 {
+			task = q.tasks[0]
+			q.tasks[0] = nil
+			q.tasks = q.tasks[1:]
+			q.mu.Unlock()
+			return task, true
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return nil, false
+		}
+		q.mu.Unlock()
+		<-q.ready
+	}
+}
+
+// release gives back the room of a popped task once it finished
+func (q *queue[T]) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.active--
+	if !q.closed {
+		close(q.freed)
+		q.freed = make(chan struct{})
+	}
+}
+
+// close rejects new tasks and wakes up blocked push and pop, queued tasks can still be popped
+func (q *queue[T]) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.freed)
+		close(q.ready)
+	}
+}
+
+func newQueue[T any](size int, policy QueuePolicy, timeout time.Duration) *queue[T] {
+	return &queue[T]{
+		size:    size,
+		policy:  policy,
+		timeout: timeout,
+		ready:   make(chan struct{}, 1),
+		freed:   make(chan struct{}),
+	}
+}
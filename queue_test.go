@@ -0,0 +1,211 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func queuedTask(n int) *Task[int] {
+	return NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {}).BuildTask(ints(n))
+}
+
+// TestQueueReject expects QueueReject to fail with *RejectedError wrapping ErrQueueFull once size is reached.
+func TestQueueReject(t *testing.T) {
+	q := newQueue[int](1, QueueReject, 0)
+	if _, err := q.push(context.Background(), queuedTask(1), true); err != nil {
+		t.Fatalf("push() = %v, want nil", err)
+	}
+	_, err := q.push(context.Background(), queuedTask(1), true)
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("push() = %v, want *RejectedError wrapping ErrQueueFull", err)
+	}
+	if rejected.Policy != QueueReject {
+		t.Fatalf("Policy = %d, want QueueReject", rejected.Policy)
+	}
+}
+
+// TestQueueBlockTimeout expects:
+//   - QueueBlock push to fail with ErrQueueFull after the queue timeout;
+//   - a non-blocking push to fail immediately;
+//   - a blocked push to succeed as soon as a task is released, not when it is popped.
+func TestQueueBlockTimeout(t *testing.T) {
+	q := newQueue[int](1, QueueBlock, 50*time.Millisecond)
+	if _, err := q.push(context.Background(), queuedTask(1), true); err != nil {
+		t.Fatalf("push() = %v, want nil", err)
+	}
+
+	start := time.Now()
+	if _, err := q.push(context.Background(), queuedTask(1), true); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("push() = %v, want ErrQueueFull", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("push() returned after %v, want >= 50ms", elapsed)
+	}
+	if _, err := q.push(context.Background(), queuedTask(1), false); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("non-blocking push() = %v, want ErrQueueFull", err)
+	}
+
+	q = newQueue[int](1, QueueBlock, 0)
+	q.push(context.Background(), queuedTask(1), true)
+	q.pop()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		q.release()
+	}()
+	if _, err := q.push(context.Background(), queuedTask(1), true); err != nil {
+		t.Fatalf("blocked push() = %v, want nil", err)
+	}
+}
+
+// TestQueueBlockContext expects a blocked push to give up with the context error.
+func TestQueueBlockContext(t *testing.T) {
+	q := newQueue[int](1, QueueBlock, 0)
+	q.push(context.Background(), queuedTask(1), true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := q.push(ctx, queuedTask(1), true)
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("push() = %v, want *RejectedError wrapping context.DeadlineExceeded", err)
+	}
+}
+
+// TestQueueDropOldest expects the oldest queued task to be evicted and pop to return the remaining tasks in order.
+func TestQueueDropOldest(t *testing.T) {
+	q := newQueue[int](2, QueueDropOldest, 0)
+	first, second, third := queuedTask(1), queuedTask(2), queuedTask(3)
+	q.push(context.Background(), first, true)
+	q.push(context.Background(), second, true)
+
+	dropped, err := q.push(context.Background(), third, false)
+	if err != nil {
+		t.Fatalf("push() = %v, want nil", err)
+	}
+	if dropped != first {
+		t.Fatal("expected oldest task dropped")
+	}
+	for _, want := range []*Task[int]{second, third} {
+		if got, ok := q.pop(); !ok || got != want {
+			t.Fatal("unexpected pop order")
+		}
+	}
+}
+
+// TestQueueClose expects close to wake up a blocked push with ErrExecutorStopped and pop to drain queued tasks.
+func TestQueueClose(t *testing.T) {
+	q := newQueue[int](1, QueueBlock, 0)
+	q.push(context.Background(), queuedTask(1), true)
+
+	errCh := make(chan error)
+	go func() {
+		_, err := q.push(context.Background(), queuedTask(1), true)
+		errCh <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	q.close()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, ErrExecutorStopped) {
+			t.Fatalf("push() = %v, want ErrExecutorStopped", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("blocked push() not woken up by close()")
+	}
+	if _, ok := q.pop(); !ok {
+		t.Fatal("expected queued task after close()")
+	}
+	if _, ok := q.pop(); ok {
+		t.Fatal("expected drained queue after close()")
+	}
+}
+
+// TestTrySubmitAndSubmitContext expects TrySubmit and SubmitContext to run all params when the queue has room.
+func TestTrySubmitAndSubmitContext(t *testing.T) {
+	p := NewConcurrentExecutor[int](4, WithQueueSize(8), WithQueuePolicy(QueueReject))
+	defer p.Stop()
+
+	f1 := p.TrySubmit(queuedTask(5))
+	f2 := p.SubmitContext(context.Background(), queuedTask(7))
+	if err := All(f1, f2).WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	if got := p.Counter().Completed(); got != 12 {
+		t.Fatalf("Completed() = %d, want 12", got)
+	}
+}
+
+// TestRejectedTaskCounter expects the params of a rejected task to count as canceled, not pending.
+func TestRejectedTaskCounter(t *testing.T) {
+	p := NewConcurrentExecutor[int](4)
+	p.Stop()
+
+	task := queuedTask(3)
+	p.counter.addPending(3)
+	task.counter = newCounter(p.counter)
+	task.future = newFuture()
+	p.track(task)
+	p.reject(task, &RejectedError{Policy: QueueReject, Err: ErrQueueFull})
+
+	if !errors.Is(task.future.Error(), ErrQueueFull) {
+		t.Fatalf("Error() = %v, want ErrQueueFull", task.future.Error())
+	}
+	assertCounterZeroPending(t, p.Counter())
+	if got := p.Counter().Canceled(); got != 3 {
+		t.Fatalf("Canceled() = %d, want 3", got)
+	}
+}
+
+// TestExecutorQueueFull expects:
+//   - a running task to keep its room in the queue of a saturated executor so TrySubmit fails with ErrQueueFull;
+//   - the room to be given back once the task finished.
+func TestExecutorQueueFull(t *testing.T) {
+	p := NewConcurrentExecutor[int](1, WithQueueSize(1), WithQueuePolicy(QueueReject))
+	defer p.Stop()
+	release := make(chan struct{})
+	running := p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		<-release
+	}).BuildTask(ints(1)))
+	waitUntil(t, time.Second, func() bool { return running.Counter().Running() == 1 })
+
+	for range 200 {
+		if err := p.TrySubmit(queuedTask(1)).WaitTimeout(time.Second); !errors.Is(err, ErrQueueFull) {
+			t.Fatalf("TrySubmit() = %v, want ErrQueueFull", err)
+		}
+	}
+	close(release)
+	running.Wait()
+	waitUntil(t, time.Second, func() bool {
+		f := p.TrySubmit(queuedTask(1))
+		f.Wait()
+		return f.Error() == nil
+	})
+}
+
+// TestQueueUnboundedDefault expects an executor with default options not to bound its running tasks, a Submit
+// returning while 100 tasks are running.
+func TestQueueUnboundedDefault(t *testing.T) {
+	p := NewConcurrentExecutor[int](1000)
+	defer p.Stop()
+	release := make(chan struct{})
+	defer close(release)
+	submitted := make(chan struct{})
+	go func() {
+		defer close(submitted)
+		for range 100 {
+			p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+				<-release
+			}).BuildTask(ints(1)))
+		}
+	}()
+	select {
+	case <-submitted:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Submit blocked on running tasks with default options")
+	}
+	waitUntil(t, 3*time.Second, func() bool { return p.Counter().Running() == 100 })
+}
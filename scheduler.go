@@ -0,0 +1,378 @@
+package conrate
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+
+	"github.com/riete/robinx"
+)
+
+// Scheduler decides which task gets the next executor token. A task is added once dispatched and removed once its
+// params are done. A token goes only to a ready task, it is held until a task is dispatched or takes it if Next
+// returns nil or no ready task. Methods are called concurrently.
+type Scheduler[T any] interface {
+	Add(task *Task[T])
+	Remove(task *Task[T])
+	Next() *Task[T]
+}
+
+// nextReady asks s for the next task until it returns a ready task, nil once it returned each of its n tasks or
+// maxTries tasks which are not ready
+func nextReady[T any](s Scheduler[T], n int) *Task[T] {
+	const maxTries = 1024
+	var tried []*Task[T]
+	for range maxTries {
+		task := s.Next()
+		if task == nil {
+			return nil
+		}
+		if task.Ready() {
+			return task
+		}
+		if !slices.Contains(tried, task) {
+			if tried = append(tried, task); len(tried) >= n {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// SchedulingPolicy selects a built-in Scheduler
+type SchedulingPolicy int64
+
+const (
+	// SchedulingSWRR shares tokens between tasks by smooth weighted round robin on their weight
+	SchedulingSWRR SchedulingPolicy = iota
+	// SchedulingEDF gives tokens to the task with the earliest deadline which can take one, tasks without
+	// TaskBuilder.WithDeadline share the tokens left by smooth weighted round robin
+	SchedulingEDF
+	// SchedulingFIFO gives tokens to the first dispatched task which can take one
+	SchedulingFIFO
+	// SchedulingPriority gives tokens to the task with the highest priority which can take one, ties are FIFO
+	SchedulingPriority
+	// SchedulingLottery draws the task of each token among the tasks which can take one, weighted by their weight
+	SchedulingLottery
+	// SchedulingDRR shares tokens between tasks by deficit round robin with their weight as quantum
+	SchedulingDRR
+)
+
+func newScheduler[T any](policy SchedulingPolicy) Scheduler[T] {
+	switch policy {
+	case SchedulingEDF:
+		return NewEDFScheduler[T]()
+	case SchedulingFIFO:
+		return NewFIFOScheduler[T]()
+	case SchedulingPriority:
+		return NewPriorityScheduler[T]()
+	case SchedulingLottery:
+		return NewLotteryScheduler[T]()
+	case SchedulingDRR:
+		return NewDRRScheduler[T]()
+	default:
+		return NewSWRRScheduler[T]()
+	}
+}
+
+type swrrScheduler[T any] struct {
+	picker robinx.Picker[*Task[T]]
+}
+
+func (s *swrrScheduler[T]) Add(task *Task[T]) {
+	task.weightedItemId = s.picker.Add(task, int64(task.weight))
+}
+
+func (s *swrrScheduler[T]) Remove(task *Task[T]) {
+	s.picker.Remove(task.weightedItemId)
+}
+
+func (s *swrrScheduler[T]) Next() *Task[T] {
+	item := s.picker.Next()
+	if item == nil {
+		return nil
+	}
+	return item.Item()
+}
+
+// NewSWRRScheduler is the default Scheduler, see SchedulingSWRR
+func NewSWRRScheduler[T any]() Scheduler[T] {
+	return &swrrScheduler[T]{picker: robinx.NewSmoothWeightedPicker[*Task[T]]()}
+}
+
+// taskList holds tasks in dispatch order
+type taskList[T any] struct {
+	tasks []*Task[T]
+	mu    sync.Mutex
+}
+
+func (l *taskList[T]) Add(task *Task[T]) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tasks = append(l.tasks, task)
+}
+
+func (l *taskList[T]) Remove(task *Task[T]) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tasks = slices.DeleteFunc(l.tasks, func(t *Task[T]) bool { return t == task })
+}
+
+// first returns the first ready task for which better is false against every other ready task
+func (l *taskList[T]) first(better func(a, b *Task[T]) bool) *Task[T] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var first *Task[T]
+	for _, task := range l.tasks {
+		if task.Ready() && (first == nil || better(task, first)) {
+			first = task
+		}
+	}
+	return first
+}
+
+type edfScheduler[T any] struct {
+	taskList[T]
+	fallback Scheduler[T]
+}
+
+func (e *edfScheduler[T]) Add(task *Task[T]) {
+	if task.deadline.IsZero() {
+		e.fallback.Add(task)
+	} else {
+		e.taskList.Add(task)
+	}
+}
+
+func (e *edfScheduler[T]) Remove(task *Task[T]) {
+	if task.deadline.IsZero() {
+		e.fallback.Remove(task)
+	} else {
+		e.taskList.Remove(task)
+	}
+}
+
+func (e *edfScheduler[T]) Next() *Task[T] {
+	if task := e.first(func(a, b *Task[T]) bool { return a.deadline.Before(b.deadline) }); task != nil {
+		return task
+	}
+	return e.fallback.Next()
+}
+
+// NewEDFScheduler see SchedulingEDF
+func NewEDFScheduler[T any]() Scheduler[T] {
+	return &edfScheduler[T]{fallback: NewSWRRScheduler[T]()}
+}
+
+type fifoScheduler[T any] struct {
+	taskList[T]
+}
+
+func (f *fifoScheduler[T]) Next() *Task[T] {
+	return f.first(func(a, b *Task[T]) bool { return false })
+}
+
+// NewFIFOScheduler see SchedulingFIFO
+func NewFIFOScheduler[T any]() Scheduler[T] {
+	return new(fifoScheduler[T])
+}
+
+type priorityScheduler[T any] struct {
+	taskList[T]
+}
+
+func (p *priorityScheduler[T]) Next() *Task[T] {
+	return p.first(func(a, b *Task[T]) bool { return a.priority > b.priority })
+}
+
+// NewPriorityScheduler see SchedulingPriority and TaskBuilder.WithPriority
+func NewPriorityScheduler[T any]() Scheduler[T] {
+	return new(priorityScheduler[T])
+}
+
+type lotteryScheduler[T any] struct {
+	taskList[T]
+}
+
+func (l *lotteryScheduler[T]) Next() *Task[T] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tickets := 0
+	for _, task := range l.tasks {
+		if task.Ready() {
+			tickets += task.weight
+		}
+	}
+	if tickets == 0 {
+		return nil
+	}
+	draw := rand.IntN(tickets)
+	for _, task := range l.tasks {
+		if !task.Ready() {
+			continue
+		}
+		if draw -= task.weight; draw < 0 {
+			return task
+		}
+	}
+	return nil
+}
+
+// NewLotteryScheduler see SchedulingLottery
+func NewLotteryScheduler[T any]() Scheduler[T] {
+	return new(lotteryScheduler[T])
+}
+
+// drr is a deficit round robin over flows, a visited flow gets its weight as deficit and a token per unit of deficit
+type drr[F comparable] struct {
+	flows   []F
+	deficit map[F]int
+	turn    int
+}
+
+func (d *drr[F]) add(flow F) {
+	d.flows = append(d.flows, flow)
+}
+
+func (d *drr[F]) remove(flow F) {
+	if i := slices.Index(d.flows, flow); i >= 0 {
+		d.flows = slices.Delete(d.flows, i, i+1)
+		if i < d.turn {
+			d.turn--
+		}
+	}
+	delete(d.deficit, flow)
+}
+
+// next returns the flow of the next token, ok is false after a full round without a ready flow
+func (d *drr[F]) next(weight func(F) int, ready func(F) bool) (flow F, ok bool) {
+	for range len(d.flows) + 1 {
+		if len(d.flows) == 0 {
+			break
+		}
+		if d.turn >= len(d.flows) {
+			d.turn = 0
+		}
+		flow = d.flows[d.turn]
+		if ready(flow) && d.deficit[flow] > 0 {
+			d.deficit[flow]--
+			return flow, true
+		}
+		// an idle flow does not save up deficit
+		d.deficit[flow] = 0
+		d.turn = (d.turn + 1) % len(d.flows)
+		d.deficit[d.flows[d.turn]] += weight(d.flows[d.turn])
+	}
+	var none F
+	return none, false
+}
+
+func newDRR[F comparable]() drr[F] {
+	return drr[F]{deficit: make(map[F]int)}
+}
+
+type drrScheduler[T any] struct {
+	drr[*Task[T]]
+	mu sync.Mutex
+}
+
+func (d *drrScheduler[T]) Add(task *Task[T]) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.add(task)
+}
+
+func (d *drrScheduler[T]) Remove(task *Task[T]) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.remove(task)
+}
+
+func (d *drrScheduler[T]) Next() *Task[T] {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	task, _ := d.next((*Task[T]).Weight, (*Task[T]).Ready)
+	return task
+}
+
+// NewDRRScheduler see SchedulingDRR
+func NewDRRScheduler[T any]() Scheduler[T] {
+	return &drrScheduler[T]{drr: newDRR[*Task[T]]()}
+}
+
+// tenant is the share of the tasks of one tenant in a tenantScheduler
+type tenant[T any] struct {
+	name      string
+	weight    int
+	tasks     []*Task[T]
+	scheduler Scheduler[T]
+}
+
+func (t *tenant[T]) ready() bool {
+	return slices.ContainsFunc(t.tasks, (*Task[T]).Ready)
+}
+
+// tenantScheduler shares tokens between tenants by deficit round robin on their weight, then between the tasks
+// of a tenant with a scheduler of its own
+type tenantScheduler[T any] struct {
+	drr          drr[*tenant[T]]
+	tenants      map[string]*tenant[T]
+	weights      map[string]int
+	newScheduler func() Scheduler[T]
+	mu           sync.Mutex
+}
+
+func (s *tenantScheduler[T]) Add(task *Task[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tenants[task.tenant]
+	if !ok {
+		t = &tenant[T]{name: task.tenant, weight: max(s.weights[task.tenant], 1), scheduler: s.newScheduler()}
+		s.tenants[task.tenant] = t
+		s.drr.add(t)
+	}
+	t.tasks = append(t.tasks, task)
+	t.scheduler.Add(task)
+}
+
+func (s *tenantScheduler[T]) Remove(task *Task[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tenants[task.tenant]
+	if !ok {
+		return
+	}
+	t.tasks = slices.DeleteFunc(t.tasks, func(other *Task[T]) bool { return other == task })
+	t.scheduler.Remove(task)
+	if len(t.tasks) == 0 {
+		delete(s.tenants, task.tenant)
+		s.drr.remove(t)
+	}
+}
+
+func (s *tenantScheduler[T]) Next() *Task[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.drr.next(func(t *tenant[T]) int { return t.weight }, (*tenant[T]).ready)
+	if !ok {
+		return nil
+	}
+	// the tenant is charged for ready tasks only, a task which is not ready is not handed out
+	task := nextReady(t.scheduler, len(t.tasks))
+	if task == nil {
+		s.drr.deficit[t]++
+	}
+	return task
+}
+
+// NewTenantScheduler shares tokens fairly between the tenants of tasks, see TaskBuilder.WithTenant, by deficit round
+// robin with weights as quantum, a tenant missing from weights weighs 1. The tasks of a tenant share its tokens with
+// a Scheduler of their own made by newScheduler.
+func NewTenantScheduler[T any](weights map[string]int, newScheduler func() Scheduler[T]) Scheduler[T] {
+	return &tenantScheduler[T]{
+		drr:          newDRR[*tenant[T]](),
+		tenants:      make(map[string]*tenant[T]),
+		weights:      weights,
+		newScheduler: newScheduler,
+	}
+}
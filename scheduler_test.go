@@ -0,0 +1,107 @@
+package conrate
+
+import (
+	"math"
+	"testing"
+)
+
+// schedulers are the built-in schedulers every Scheduler test runs against.
+var schedulers = []struct {
+	name string
+	new  func() Scheduler[int]
+}{
+	{"SWRR", NewSWRRScheduler[int]},
+	{"EDF", NewEDFScheduler[int]},
+	{"FIFO", NewFIFOScheduler[int]},
+	{"Priority", NewPriorityScheduler[int]},
+	{"Lottery", NewLotteryScheduler[int]},
+	{"DRR", NewDRRScheduler[int]},
+}
+
+// schedulable builds a dispatched-like task taking one token at a time.
+func schedulable(weight, priority int) *Task[int] {
+	task := NewTaskBuilder[int]().WithWeight(weight).WithPriority(priority).BuildTask(ints(1))
+	task.wait = make(chan struct{}, 1)
+	return task
+}
+
+// TestSchedulerConformance expects every Scheduler:
+//   - to return nil without tasks;
+//   - to only return added tasks and to give a token to a single ready task;
+//   - to never return a removed task.
+func TestSchedulerConformance(t *testing.T) {
+	for _, sc := range schedulers {
+		s := sc.new()
+		if task := s.Next(); task != nil {
+			t.Fatalf("%s: Next() = %v without tasks, want nil", sc.name, task)
+		}
+		a, b := schedulable(3, 0), schedulable(1, 0)
+		s.Add(a)
+		if task := s.Next(); task != a {
+			t.Fatalf("%s: Next() = %v, want the single task", sc.name, task)
+		}
+		s.Add(b)
+		for range 100 {
+			if task := s.Next(); task != a && task != b {
+				t.Fatalf("%s: Next() = %v, want an added task", sc.name, task)
+			}
+		}
+		s.Remove(a)
+		for range 100 {
+			if task := s.Next(); task != b {
+				t.Fatalf("%s: Next() = %v after Remove, want the remaining task", sc.name, task)
+			}
+		}
+		s.Remove(b)
+		if task := s.Next(); task != nil {
+			t.Fatalf("%s: Next() = %v after removing every task, want nil", sc.name, task)
+		}
+	}
+}
+
+// TestSchedulerPolicies expects:
+//   - FIFO to pick the first added ready task and Priority the highest priority ready task;
+//   - SWRR, Lottery and DRR to share tokens by weight, 3:1 gives the heavy task about 75% of tokens.
+func TestSchedulerPolicies(t *testing.T) {
+	first, low, high := schedulable(1, 0), schedulable(1, 1), schedulable(1, 2)
+	fifo := NewFIFOScheduler[int]()
+	priority := NewPriorityScheduler[int]()
+	for _, task := range []*Task[int]{first, low, high} {
+		fifo.Add(task)
+		priority.Add(task)
+	}
+	if task := fifo.Next(); task != first {
+		t.Fatalf("FIFO: Next() = priority %d, want the first task", task.Priority())
+	}
+	if task := priority.Next(); task != high {
+		t.Fatalf("Priority: Next() = priority %d, want 2", task.Priority())
+	}
+	first.wait <- struct{}{}
+	high.wait <- struct{}{}
+	if task := fifo.Next(); task != low {
+		t.Fatalf("FIFO: Next() = priority %d, want the first ready task", task.Priority())
+	}
+	if task := priority.Next(); task != low {
+		t.Fatalf("Priority: Next() = priority %d, want the highest ready task", task.Priority())
+	}
+
+	for _, sc := range schedulers {
+		if sc.name != "SWRR" && sc.name != "Lottery" && sc.name != "DRR" {
+			continue
+		}
+		s := sc.new()
+		heavy, light := schedulable(3, 0), schedulable(1, 0)
+		s.Add(heavy)
+		s.Add(light)
+		const n = 4000
+		got := 0
+		for range n {
+			if s.Next() == heavy {
+				got++
+			}
+		}
+		if share := float64(got) / n; math.Abs(share-0.75) > 0.05 {
+			t.Fatalf("%s: heavy task got %.2f of tokens, want 0.75", sc.name, share)
+		}
+	}
+}
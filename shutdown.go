@@ -0,0 +1,96 @@
+package conrate
+
+import "context"
+
+// Report is the state of an executor at the end of Executor.Shutdown, params are counted over the executor lifetime
+type Report struct {
+	// Completed params have run to the end
+	Completed int64
+	// Canceled params never started and never will
+	Canceled int64
+	// Abandoned params were still running when the shutdown context was done
+	Abandoned int64
+	// Running are the futures of tasks still running when the shutdown context was done
+	Running []*Future
+}
+
+// Shutdown no new tasks can be submitted, params which have not started are canceled unless WithDrainOnShutdown,
+// it waits for running params until ctx is done and returns ctx.Err() if they did not finish in time
+func (e *Executor[T]) Shutdown(ctx context.Context) (Report, error) {
+	return e.shutdown(ctx, e.options.drain)
+}
+
+func (e *Executor[T]) shutdown(ctx context.Context, drain bool) (Report, error) {
+	e.mu.Lock()
+	if e.state >= StateStopping {
+		e.mu.Unlock()
+		return e.report(), ErrExecutorStopped
+	}
+	e.state = StateStopping
+	lc := e.lc
+	lc.delayed.close()
+	lc.queue.close()
+	if !drain {
+		close(lc.stop)
+	}
+	e.mu.Unlock()
+
+	err := e.waitIdle(ctx)
+	report := e.report()
+	if drain {
+		close(lc.stop)
+	}
+	lc.limiter.Stop()
+	e.mu.Lock()
+	e.state = StateStopped
+	e.mu.Unlock()
+	return report, err
+}
+
+// waitIdle waits until no task is active or ctx is done
+func (e *Executor[T]) waitIdle(ctx context.Context) error {
+	for {
+		e.activeMu.Lock()
+		n := len(e.active)
+		e.activeMu.Unlock()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-e.idleTask:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (e *Executor[T]) report() Report {
+	report := Report{
+		Completed: e.counter.Completed(),
+		Canceled:  e.counter.Canceled() + e.counter.Pending(),
+		Abandoned: e.counter.Running(),
+	}
+	e.activeMu.Lock()
+	defer e.activeMu.Unlock()
+	for task := range e.active {
+		report.Running = append(report.Running, task.future)
+	}
+	return report
+}
+
+// track marks task active from submit until it is done
+func (e *Executor[T]) track(task *Task[T]) {
+	e.activeMu.Lock()
+	defer e.activeMu.Unlock()
+	e.active[task] = struct{}{}
+}
+
+func (e *Executor[T]) untrack(task *Task[T]) {
+	e.activeMu.Lock()
+	delete(e.active, task)
+	e.activeMu.Unlock()
+	select {
+	case e.idleTask <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,82 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestGracefulStopReturnsEarly expects GracefulStop to return as soon as the last task finishes, not after a polling interval.
+func TestGracefulStopReturnsEarly(t *testing.T) {
+	p := NewConcurrentExecutor[int](100)
+
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		time.Sleep(20 * time.Millisecond)
+	}).BuildTask(ints(4)))
+	waitUntil(t, 3*time.Second, func() bool { return p.Counter().Running() > 0 })
+
+	start := time.Now()
+	p.GracefulStop(5 * time.Second)
+	if elapsed := time.Since(start); elapsed >= 500*time.Millisecond {
+		t.Fatalf("GracefulStop() took %v, want < 500ms", elapsed)
+	}
+	if err := f.Error(); err != nil {
+		t.Fatalf("Error() = %v, want nil", err)
+	}
+}
+
+// TestShutdownCancelsQueued expects:
+//   - Shutdown without drain to cancel params which have not started;
+//   - ctx.Err() when a running param outlives ctx, reported as Abandoned with its task in Running.
+func TestShutdownCancelsQueued(t *testing.T) {
+	p := NewConcurrentExecutor[int](1)
+
+	release := make(chan struct{})
+	defer close(release)
+	const n = 10
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		<-release
+	}).BuildTask(ints(n)))
+	waitUntil(t, 3*time.Second, func() bool { return p.Counter().Running() == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	report, err := p.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown() = %v, want context.DeadlineExceeded", err)
+	}
+	if report.Abandoned != 1 || report.Completed != 0 || report.Canceled != n-1 {
+		t.Fatalf("report = %+v, want 1 abandoned, 0 completed and %d canceled", report, n-1)
+	}
+	if len(report.Running) != 1 || report.Running[0] != f.Tasks()[0] {
+		t.Fatalf("report.Running = %v, want the submitted task", report.Running)
+	}
+	if !p.IsStopped() {
+		t.Fatal("expected executor stopped after Shutdown()")
+	}
+}
+
+// TestShutdownDrain expects WithDrainOnShutdown to run every queued param before Shutdown returns.
+func TestShutdownDrain(t *testing.T) {
+	p := NewConcurrentExecutor[int](2, WithDrainOnShutdown(true))
+
+	const n = 10
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		time.Sleep(10 * time.Millisecond)
+	}).BuildTask(ints(n)))
+
+	report, err := p.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("Shutdown() = %v, want nil", err)
+	}
+	if report.Completed != n || report.Canceled != 0 || report.Abandoned != 0 || len(report.Running) != 0 {
+		t.Fatalf("report = %+v, want %d completed only", report, n)
+	}
+	if err := f.Error(); err != nil {
+		t.Fatalf("Error() = %v, want nil", err)
+	}
+	if _, err := p.Shutdown(context.Background()); !errors.Is(err, ErrExecutorStopped) {
+		t.Fatalf("second Shutdown() = %v, want ErrExecutorStopped", err)
+	}
+}
@@ -0,0 +1,50 @@
+package conrate
+
+// block runs wait which blocks on a limit of task itself, tokens buffered for task are reclaimed for other tasks
+// meanwhile and task is not Ready until wait returns
+func (e *Executor[T]) block(task *Task[T], wait func() error) error {
+	task.blocked.Store(true)
+	e.reclaim(task)
+	err := wait()
+	task.blocked.Store(false)
+	task.lc.signal()
+	return err
+}
+
+// reclaim moves the tokens buffered for task to the spare tokens schedule hands out first, a token beyond the room
+// for spare tokens is dropped
+func (e *Executor[T]) reclaim(task *Task[T]) {
+	for {
+		select {
+		case <-task.wait:
+			select {
+			case task.lc.spare <- struct{}{}:
+			default:
+			}
+		default:
+			return
+		}
+	}
+}
+
+// token takes a spare token, else a token of the limiter, false once lc stopped. A spare token reclaimed while
+// schedule waits for the limiter is taken instead and spare tokens are dropped while the limiter holds tokens back.
+func (e *Executor[T]) token(lc *lifecycle[T]) bool {
+	for {
+		select {
+		case <-lc.spare:
+		default:
+			if err := lc.limiter.take(1, lc.stop, lc.spare); err == ErrLimiterStopped {
+				return false
+			}
+		}
+		select {
+		case <-lc.stop:
+			return false
+		default:
+		}
+		if !lc.limiter.holding() {
+			return true
+		}
+	}
+}
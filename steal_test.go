@@ -0,0 +1,95 @@
+package conrate
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestStealing expects:
+//   - a task blocked on WithMaxInFlight not to be Ready;
+//   - the tokens buffered for it to run another task at once instead of waiting for the limiter to refill.
+func TestStealing(t *testing.T) {
+	p := NewRateLimitExecutor[int](10)
+	defer p.Stop()
+	var started atomic.Int64
+	release := make(chan struct{})
+	capped := NewTaskBuilder[int]().WithWeight(5).WithMaxInFlight(5).WithTaskFunc(func(context.Context, int) {
+		started.Add(1)
+		<-release
+	}).BuildTask(ints(10))
+	f := p.Submit(capped)
+	waitUntil(t, time.Second, func() bool { return started.Load() == 5 && !capped.Ready() })
+	time.Sleep(50 * time.Millisecond)
+
+	light := p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {}).BuildTask(ints(5)))
+	if err := light.WaitTimeout(300 * time.Millisecond); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	close(release)
+	if err := f.WaitTimeout(3 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	if got := f.Counter().Completed(); got != 10 {
+		t.Fatalf("Completed() = %d, want 10", got)
+	}
+}
+
+// TestFinishReclaims expects the tokens buffered for a task and not taken once it finished to run another task at
+// once instead of being dropped.
+func TestFinishReclaims(t *testing.T) {
+	p := NewRateLimitExecutor[int](10)
+	defer p.Stop()
+	done := p.Submit(NewTaskBuilder[int]().WithWeight(10).WithTaskFunc(func(context.Context, int) {
+		time.Sleep(50 * time.Millisecond)
+	}).BuildTask(ints(1)))
+	if err := done.WaitTimeout(time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+
+	var started atomic.Int64
+	f := p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+		started.Add(1)
+	}).BuildTask(ints(20)))
+	defer f.Cancel()
+	time.Sleep(300 * time.Millisecond)
+	if got := started.Load(); got < 8 {
+		t.Fatalf("%d params started in 300ms, want >= 8", got)
+	}
+}
+
+// benchmarkStealing runs capped tasks which block on WithMaxInFlight next to an uncapped task, utilization is the
+// share of the tokens the executor handed out, its burst and capacity per second, which started a param
+func benchmarkStealing(b *testing.B, capped int) {
+	const capacity = 100
+	var started atomic.Int64
+	var tokens float64
+	for range b.N {
+		start := time.Now()
+		p := NewRateLimitExecutor[int](capacity)
+		var tasks []*Task[int]
+		for range capped {
+			tasks = append(tasks, NewTaskBuilder[int]().WithWeight(5).WithMaxInFlight(5).WithTaskFunc(func(ctx context.Context, _ int) {
+				started.Add(1)
+				<-ctx.Done()
+			}).BuildTask(ints(100)))
+		}
+		blocked := p.Submit(tasks...)
+		p.Submit(NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+			started.Add(1)
+		}).BuildTask(ints(3 * capacity))).Wait()
+		tokens += capacity * (1 + time.Since(start).Seconds())
+		blocked.Cancel()
+		p.Stop()
+	}
+	b.ReportMetric(float64(started.Load())/tokens, "utilization")
+}
+
+func BenchmarkStealingUncapped(b *testing.B) {
+	benchmarkStealing(b, 0)
+}
+
+func BenchmarkStealingCapped(b *testing.B) {
+	benchmarkStealing(b, 10)
+}
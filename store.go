@@ -0,0 +1,205 @@
+package conrate
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"sync"
+)
+
+var ErrCorruptStore = errors.New("corrupt store log")
+
+// TaskRecord is a persisted task, Params are the encoded params not processed yet keyed by their index in the task
+type TaskRecord struct {
+	ID     string
+	Name   string
+	Params map[int][]byte
+}
+
+// Store persists submitted tasks and the progress of their params
+type Store interface {
+	// Save creates or replaces the record of a task
+	Save(record TaskRecord) error
+	// MarkDone removes param index from the unprocessed params of task id
+	MarkDone(id string, index int) error
+	// Delete forgets task id
+	Delete(id string) error
+	// Load returns the records of all tasks which are not deleted
+	Load() ([]TaskRecord, error)
+	Close() error
+}
+
+type fileEntry struct {
+	Op     string         `json:"op"`
+	ID     string         `json:"id"`
+	Name   string         `json:"name,omitempty"`
+	Params map[int][]byte `json:"params,omitempty"`
+	Index  int            `json:"index,omitempty"`
+}
+
+const (
+	opSave   = "save"
+	opDone   = "done"
+	opDelete = "delete"
+)
+
+// FileStore is an embedded Store backed by an append-only log file, the log is compacted when the store is opened
+type FileStore struct {
+	path    string
+	file    *os.File
+	records map[string]*TaskRecord
+	mu      sync.Mutex
+}
+
+func (f *FileStore) apply(entry fileEntry) {
+	switch entry.Op {
+	case opSave:
+		f.records[entry.ID] = &TaskRecord{ID: entry.ID, Name: entry.Name, Params: maps.Clone(entry.Params)}
+	case opDone:
+		if record, ok := f.records[entry.ID]; ok {
+			delete(record.Params, entry.Index)
+		}
+	case opDelete:
+		delete(f.records, entry.ID)
+	}
+}
+
+func (f *FileStore) write(entry fileEntry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err = f.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	f.apply(entry)
+	return nil
+}
+
+func (f *FileStore) Save(record TaskRecord) error {
+	return f.write(fileEntry{Op: opSave, ID: record.ID, Name: record.Name, Params: record.Params})
+}
+
+func (f *FileStore) MarkDone(id string, index int) error {
+	return f.write(fileEntry{Op: opDone, ID: id, Index: index})
+}
+
+func (f *FileStore) Delete(id string) error {
+	return f.write(fileEntry{Op: opDelete, ID: id})
+}
+
+func (f *FileStore) Load() ([]TaskRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	records := make([]TaskRecord, 0, len(f.records))
+	for _, record := range f.records {
+		records = append(records, TaskRecord{ID: record.ID, Name: record.Name, Params: maps.Clone(record.Params)})
+	}
+	return records, nil
+}
+
+func (f *FileStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// replay applies the log, a truncated last entry left by a crash is ignored and a corrupt entry before it fails
+// with ErrCorruptStore since compacting would lose the entries after it
+func (f *FileStore) replay() error {
+	file, err := os.Open(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		last := err != nil
+		if !last {
+			_, peekErr := reader.Peek(1)
+			last = errors.Is(peekErr, io.EOF)
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			var entry fileEntry
+			if decodeErr := json.Unmarshal(data, &entry); decodeErr != nil {
+				if last {
+					return nil
+				}
+				return fmt.Errorf("%w: %s line %d: %v", ErrCorruptStore, f.path, line, decodeErr)
+			}
+			f.apply(entry)
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// compact rewrites the log with one save entry per record
+func (f *FileStore) compact() error {
+	tmp := f.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	for _, record := range f.records {
+		if err = encoder.Encode(fileEntry{Op: opSave, ID: record.ID, Name: record.Name, Params: record.Params}); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// NewFileStore opens or creates the log file at path
+func NewFileStore(path string) (*FileStore, error) {
+	f := &FileStore{path: path, records: make(map[string]*TaskRecord)}
+	if err := f.replay(); err != nil {
+		return nil, err
+	}
+	if err := f.compact(); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	f.file = file
+	return f, nil
+}
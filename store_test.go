@@ -0,0 +1,155 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestFileStoreReplay expects:
+//   - Save, MarkDone and Delete to survive reopening the store;
+//   - a truncated last entry left by a crash to be ignored.
+func TestFileStoreReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.log")
+	fs, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore() = %v", err)
+	}
+	fs.Save(TaskRecord{ID: "a", Name: "backfill", Params: map[int][]byte{0: []byte("0"), 1: []byte("1"), 2: []byte("2")}})
+	fs.Save(TaskRecord{ID: "b", Params: map[int][]byte{0: []byte("0")}})
+	fs.MarkDone("a", 1)
+	fs.Delete("b")
+	fs.Close()
+
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	file.WriteString(`{"op":"done","id":"a","ind`)
+	file.Close()
+
+	fs, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore() = %v", err)
+	}
+	defer fs.Close()
+	records, _ := fs.Load()
+	if len(records) != 1 || records[0].ID != "a" || records[0].Name != "backfill" {
+		t.Fatalf("Load() = %+v, want record a only", records)
+	}
+	if got := records[0].Params; len(got) != 2 || string(got[0]) != "0" || string(got[2]) != "2" {
+		t.Fatalf("Params = %v, want indexes 0 and 2", got)
+	}
+}
+
+// TestFileStoreCorrupt expects a corrupt entry before the last one to fail NewFileStore with ErrCorruptStore and to
+// leave the log as it was.
+func TestFileStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.log")
+	log := `{"op":"save","id":"a","params":{"0":"MA=="}}` + "\n" +
+		`{"op":"save","id":"b","par` + "\n" +
+		`{"op":"save","id":"c","params":{"0":"MA=="}}` + "\n"
+	if err := os.WriteFile(path, []byte(log), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileStore(path); !errors.Is(err, ErrCorruptStore) {
+		t.Fatalf("NewFileStore() = %v, want ErrCorruptStore", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != log {
+		t.Fatalf("log = %q, want it untouched", got)
+	}
+}
+
+type codecParam struct {
+	ID   int
+	Name string
+}
+
+// TestCodecs expects JSONCodec and GobCodec to round-trip a struct param.
+func TestCodecs(t *testing.T) {
+	want := codecParam{ID: 7, Name: "seven"}
+	for _, codec := range []Codec[codecParam]{JSONCodec[codecParam]{}, GobCodec[codecParam]{}} {
+		data, err := codec.Encode(want)
+		if err != nil {
+			t.Fatalf("%T Encode() = %v", codec, err)
+		}
+		got, err := codec.Decode(data)
+		if err != nil || got != want {
+			t.Fatalf("%T Decode() = %+v, %v, want %+v", codec, got, err, want)
+		}
+	}
+}
+
+// TestResumeFrom expects:
+//   - params processed before Stop not to run again after ResumeFrom;
+//   - every other param to run exactly once after ResumeFrom;
+//   - the record to be deleted once the resumed task finishes.
+func TestResumeFrom(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.log")
+	fs, _ := NewFileStore(path)
+
+	var mu sync.Mutex
+	var ran []int
+	release := make(chan struct{})
+	p := NewConcurrentExecutor[int](100, WithStore(fs), WithCodec[int](GobCodec[int]{}))
+	f := p.Submit(NewTaskBuilder[int]().WithName("backfill").WithMaxConcurrency(1).WithTaskFunc(func(_ context.Context, i int) {
+		if i >= 3 {
+			<-release
+		}
+		mu.Lock()
+		ran = append(ran, i)
+		mu.Unlock()
+	}).BuildTask(ints(10)))
+	waitUntil(t, 5*time.Second, func() bool { return p.Counter().Completed() == 3 && p.Counter().Running() == 1 })
+	p.Stop()
+	close(release)
+	f.Wait()
+	fs.Close()
+
+	fs, _ = NewFileStore(path)
+	defer fs.Close()
+	p = NewConcurrentExecutor[int](100, WithStore(fs), WithCodec[int](GobCodec[int]{}))
+	defer p.Stop()
+	builder := func(name string) *TaskBuilder[int] {
+		if name != "backfill" {
+			return nil
+		}
+		return NewTaskBuilder[int]().WithTaskFunc(func(_ context.Context, i int) {
+			mu.Lock()
+			ran = append(ran, i)
+			mu.Unlock()
+		})
+	}
+	f, err := p.ResumeFrom(fs, builder)
+	if err != nil {
+		t.Fatalf("ResumeFrom() = %v", err)
+	}
+	if err := f.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	if got := p.Counter().Completed(); got != 6 {
+		t.Fatalf("Completed() after ResumeFrom = %d, want 6", got)
+	}
+	slices.Sort(ran)
+	if !slices.Equal(ran, ints(10)) {
+		t.Fatalf("ran %v, want each of 0..9 once", ran)
+	}
+	if records, _ := fs.Load(); len(records) != 0 {
+		t.Fatalf("Load() = %+v, want no records", records)
+	}
+}
+
+// TestResumeFromUnknownName expects ResumeFrom to fail when builder has no TaskBuilder for a recorded name.
+func TestResumeFromUnknownName(t *testing.T) {
+	fs, _ := NewFileStore(filepath.Join(t.TempDir(), "tasks.log"))
+	defer fs.Close()
+	fs.Save(TaskRecord{ID: "a", Name: "unknown", Params: map[int][]byte{0: []byte("0")}})
+
+	p := NewConcurrentExecutor[int](4)
+	defer p.Stop()
+	if _, err := p.ResumeFrom(fs, func(string) *TaskBuilder[int] { return nil }); err == nil {
+		t.Fatal("expected ResumeFrom() error for unknown task name")
+	}
+}
@@ -2,38 +2,258 @@ package conrate
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"iter"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/riete/robinx"
 )
 
-// Task runtime maxConcurrency will use min(Task.maxConcurrency, Executor.limiter.capacity) if Task.maxConcurrency > 0
-// else Executor.limiter.capacity in both ConcurrencyMode and RateLimitMode
-// On task panic, Task.recover is preferred over default recover (print panic message and goroutine stack trace)
+var ErrTaskPanic = errors.New("task panic")
+
+// ItemError is returned through Future.Error when params of a task failed, Err is the first failure
+type ItemError struct {
+	Failed int64
+	Err    error
+}
+
+func (i *ItemError) Error() string {
+	return fmt.Sprintf("%d params failed: %s", i.Failed, i.Err)
+}
+
+func (i *ItemError) Unwrap() error {
+	return i.Err
+}
+
+// BatchError is returned by a batch handler to fail some items of a batch only, Errs maps the index of an item in
+// the batch to its error
+type BatchError struct {
+	Errs map[int]error
+}
+
+func (b *BatchError) Error() string {
+	return fmt.Sprintf("%d batch items failed", len(b.Errs))
+}
+
+// Task runtime qps is min(Task.maxQPS, Executor.limiter.capacity) if Task.maxQPS > 0 and runtime concurrency is
+// Task.maxInFlight if Task.maxInFlight > 0, bounded by Executor.limiter.capacity in ConcurrencyMode.
+// Task.maxConcurrency is Task.maxQPS in RateLimitMode and Task.maxInFlight in ConcurrencyMode unless they are set.
+// On task panic, Task.recover is preferred over default recover (print panic message and goroutine stack trace),
+// the param counts as failed unless Task.recover handled the panic of a task function which cannot fail
 // Task weight is used for SWRR scheduling.
 // Use TaskBuilder to build task
 type Task[T any] struct {
+	name           string
+	id             string
+	indexes        []int
 	ctx            context.Context
-	taskFunc       func(context.Context, T)
+	runCtx         context.Context
+	taskFunc       func(context.Context, T) error
 	param          []T
+	source         <-chan T
+	drained        bool
+	batchFunc      func(context.Context, []T) error
+	batchSize      int
+	batchWait      time.Duration
+	dedupKey       func(T) string
+	leads          map[int]*flight
+	breakerKey     func(T) string
+	costFunc       func(T) int
+	deadline       time.Time
+	priority       int
+	tenant         string
 	maxConcurrency int
+	maxQPS         int
+	maxInFlight    int
 	recover        func(T, any)
+	// panicFails is false for a task built WithTaskFunc and WithRecover, its panics are handled by recover
+	panicFails     bool
 	weight         int
 	wait           chan struct{}
+	blocked        atomic.Bool
 	weightedItemId robinx.ID
-	wg             *sync.WaitGroup
+	counter        *Counter
+	future         *Future
+	err            error
+	storeErr       error
+	itemErr        error
+	lc             *lifecycle[T]
+	mu             sync.Mutex
+}
+
+func (t *Task[T]) Name() string {
+	return t.name
+}
+
+func (t *Task[T]) Weight() int {
+	return t.weight
+}
+
+func (t *Task[T]) Tenant() string {
+	return t.tenant
+}
+
+func (t *Task[T]) Priority() int {
+	return t.priority
+}
+
+// Deadline is the zero time without TaskBuilder.WithDeadline
+func (t *Task[T]) Deadline() time.Time {
+	return t.deadline
+}
+
+// Ready is true if the task can take an executor token now, it is not while the task is blocked on its own
+// WithMaxQPS or WithMaxInFlight limit. A Scheduler should prefer ready tasks.
+func (t *Task[T]) Ready() bool {
+	return !t.blocked.Load() && len(t.wait) < cap(t.wait)
 }
 
 func (t *Task[T]) done() {
-	t.wg.Done()
+	var itemErr error
+	if t.itemErr != nil {
+		itemErr = &ItemError{Failed: t.counter.Failed(), Err: t.itemErr}
+	}
+	t.future.resolve(errors.Join(t.err, itemErr, t.storeErr))
+}
+
+// index returns the index of param i in the originally submitted task
+func (t *Task[T]) index(i int) int {
+	if t.indexes != nil {
+		return t.indexes[i]
+	}
+	return i
+}
+
+// params yields the runs of the task as the index of their first param and their params, a run is one param or
+// one batch of params of a task built WithBatching. A param received from the source of a stream task counts as
+// pending and as canceled until it runs.
+func (t *Task[T]) params(canceled *atomic.Int64) iter.Seq2[int, []T] {
+	size := max(t.batchSize, 1)
+	if t.source == nil {
+		return func(yield func(int, []T) bool) {
+			for i := 0; i < len(t.param); i += size {
+				if !yield(i, t.param[i:min(i+size, len(t.param))]) {
+					return
+				}
+			}
+		}
+	}
+	return func(yield func(int, []T) bool) {
+		var batch []T
+		var flush <-chan time.Time
+		var timer *time.Timer
+		index := 0
+		emit := func() bool {
+			if timer != nil {
+				timer.Stop()
+				flush = nil
+			}
+			ok := yield(index, batch)
+			index += len(batch)
+			batch = nil
+			return ok
+		}
+		for {
+			select {
+			case <-t.lc.stop:
+				return
+			case <-t.ctx.Done():
+				return
+			case <-flush:
+				if !emit() {
+					return
+				}
+			case param, ok := <-t.source:
+				if !ok {
+					if len(batch) > 0 && !emit() {
+						return
+					}
+					t.drained = true
+					return
+				}
+				canceled.Add(1)
+				t.counter.addPending(1)
+				batch = append(batch, param)
+				switch {
+				case len(batch) >= size:
+					if !emit() {
+						return
+					}
+				case len(batch) == 1 && t.batchWait > 0:
+					timer = time.NewTimer(t.batchWait)
+					flush = timer.C
+				}
+			}
+		}
+	}
+}
+
+// lead records f as the flight led by param i, or forgets and returns the flight of param i if f is nil
+func (t *Task[T]) lead(i int, f *flight) *flight {
+	if t.dedupKey == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if f != nil {
+		if t.leads == nil {
+			t.leads = make(map[int]*flight)
+		}
+		t.leads[i] = f
+		return f
+	}
+	f = t.leads[i]
+	delete(t.leads, i)
+	return f
+}
+
+// failItems counts n failed params and keeps the first error
+func (t *Task[T]) failItems(n int64, err error) {
+	t.counter.addFailed(n)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.itemErr == nil {
+		t.itemErr = err
+	}
+}
+
+// failStore keeps the first Store error of the task
+func (t *Task[T]) failStore(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.storeErr == nil {
+		t.storeErr = err
+	}
 }
 
 type TaskBuilder[T any] struct {
+	name           string
 	ctx            context.Context
-	taskFunc       func(context.Context, T)
+	taskFunc       func(context.Context, T) error
+	fallible       bool
 	maxConcurrency int
+	maxQPS         int
+	maxInFlight    int
 	recover        func(T, any)
 	weight         int
+	batchFunc      func(context.Context, []T) error
+	batchSize      int
+	batchWait      time.Duration
+	dedupKey       func(T) string
+	breakerKey     func(T) string
+	costFunc       func(T) int
+	deadline       time.Time
+	priority       int
+	tenant         string
+}
+
+// WithName names built tasks, the name is persisted by a Store to find the TaskBuilder on Executor.ResumeFrom
+func (t *TaskBuilder[T]) WithName(name string) *TaskBuilder[T] {
+	t.name = name
+	return t
 }
 
 func (t *TaskBuilder[T]) WithContext(ctx context.Context) *TaskBuilder[T] {
@@ -41,11 +261,26 @@ func (t *TaskBuilder[T]) WithContext(ctx context.Context) *TaskBuilder[T] {
 	return t
 }
 
+// WithMaxConcurrency is WithMaxQPS in RateLimitMode and WithMaxInFlight in ConcurrencyMode
 func (t *TaskBuilder[T]) WithMaxConcurrency(maxConcurrency int) *TaskBuilder[T] {
 	t.maxConcurrency = maxConcurrency
 	return t
 }
 
+// WithMaxQPS caps the rate params of the task start at in both modes, below the executor capacity
+func (t *TaskBuilder[T]) WithMaxQPS(maxQPS int) *TaskBuilder[T] {
+	t.maxQPS = maxQPS
+	return t
+}
+
+// WithMaxInFlight caps the params of the task running at once in both modes
+func (t *TaskBuilder[T]) WithMaxInFlight(maxInFlight int) *TaskBuilder[T] {
+	t.maxInFlight = maxInFlight
+	return t
+}
+
+// WithRecover handles the panics of the task function, a param of a task built WithTaskFunc whose panic is handled
+// does not count as failed
 func (t *TaskBuilder[T]) WithRecover(recover func(T, any)) *TaskBuilder[T] {
 	t.recover = recover
 	return t
@@ -60,21 +295,110 @@ func (t *TaskBuilder[T]) WithWeight(weight int) *TaskBuilder[T] {
 }
 
 func (t *TaskBuilder[T]) WithTaskFunc(f func(context.Context, T)) *TaskBuilder[T] {
+	t.taskFunc = func(ctx context.Context, param T) error {
+		f(ctx, param)
+		return nil
+	}
+	t.fallible = false
+	return t
+}
+
+// WithBatching runs params by batches of at most maxSize with f instead of the task function, a batch takes one rate
+// token or one concurrency slot. A stream task runs a batch once maxSize params are received or maxWait after its
+// first param, maxWait <= 0 waits for a full batch. Items of a batch fail together when f returns an error, a
+// *BatchError fails only the items it holds.
+func (t *TaskBuilder[T]) WithBatching(maxSize int, maxWait time.Duration, f func(context.Context, []T) error) *TaskBuilder[T] {
+	t.batchFunc = f
+	t.batchSize = max(maxSize, 1)
+	t.batchWait = maxWait
+	return t
+}
+
+// WithDedupKey makes a param whose key is the key of a param queued or running in any task of the executor share
+// its execution and outcome instead of taking another token or slot, including the cancellation of a param which
+// never runs. Keys are shared by every task of the executor. It has no effect on tasks built WithBatching.
+func (t *TaskBuilder[T]) WithDedupKey(key func(T) string) *TaskBuilder[T] {
+	t.dedupKey = key
+	return t
+}
+
+// WithBreakerKey gives each key its own circuit breaker instead of the executor one, keys are shared by every
+// task of the executor. It has no effect without WithCircuitBreaker.
+func (t *TaskBuilder[T]) WithBreakerKey(key func(T) string) *TaskBuilder[T] {
+	t.breakerKey = key
+	return t
+}
+
+// WithCost makes a param take cost(param) rate tokens in RateLimitMode or executor concurrency slots in
+// ConcurrencyMode instead of one, a batch takes the sum of the cost of its items. A cost below 1 counts as 1, a run
+// costing more than the executor capacity fails with ErrCostExceedsCapacity without running.
+func (t *TaskBuilder[T]) WithCost(cost func(T) int) *TaskBuilder[T] {
+	t.costFunc = cost
+	return t
+}
+
+// WithDeadline makes the task context done at deadline with ErrTaskDeadline as cause, params not started by then
+// are canceled and count as Counter.Expired. Future.AtRisk turns true once the task cannot finish in time at the
+// executor capacity. Tasks with a deadline are scheduled earliest deadline first with SchedulingEDF.
+func (t *TaskBuilder[T]) WithDeadline(deadline time.Time) *TaskBuilder[T] {
+	t.deadline = deadline
+	return t
+}
+
+// WithPriority sets the priority of the task for SchedulingPriority, higher goes first, default is 0
+func (t *TaskBuilder[T]) WithPriority(priority int) *TaskBuilder[T] {
+	t.priority = priority
+	return t
+}
+
+// WithTenant makes the task share the executor tokens of tenant with the other tasks of tenant, tenants share tokens
+// by their weight, see WithTenantWeights and Executor.TenantCounter. Tasks without tenant belong to tenant "".
+func (t *TaskBuilder[T]) WithTenant(tenant string) *TaskBuilder[T] {
+	t.tenant = tenant
+	return t
+}
+
+// WithTaskFuncE is WithTaskFunc for a task function which can fail, a param returning an error counts as failed
+// and the task Future fails with an *ItemError
+func (t *TaskBuilder[T]) WithTaskFuncE(f func(context.Context, T) error) *TaskBuilder[T] {
 	t.taskFunc = f
+	t.fallible = true
 	return t
 }
 
 func (t *TaskBuilder[T]) BuildTask(param []T) *Task[T] {
 	return &Task[T]{
+		name:           t.name,
 		ctx:            t.ctx,
+		runCtx:         t.ctx,
 		taskFunc:       t.taskFunc,
 		param:          param,
 		maxConcurrency: t.maxConcurrency,
+		maxQPS:         t.maxQPS,
+		maxInFlight:    t.maxInFlight,
 		recover:        t.recover,
+		panicFails:     t.fallible || t.batchFunc != nil || t.recover == nil,
 		weight:         t.weight,
+		batchFunc:      t.batchFunc,
+		batchSize:      t.batchSize,
+		batchWait:      t.batchWait,
+		dedupKey:       t.dedupKey,
+		breakerKey:     t.breakerKey,
+		costFunc:       t.costFunc,
+		deadline:       t.deadline,
+		priority:       t.priority,
+		tenant:         t.tenant,
 	}
 }
 
+// BuildStreamTask builds a task running the params received from source until it is closed, params count as
+// pending once received. A stream task is not persisted by a Store.
+func (t *TaskBuilder[T]) BuildStreamTask(source <-chan T) *Task[T] {
+	task := t.BuildTask(nil)
+	task.source = source
+	return task
+}
+
 func (t *TaskBuilder[T]) BuildTasks(params ...[]T) []*Task[T] {
 	tasks := make([]*Task[T], 0, len(params))
 	for _, param := range params {
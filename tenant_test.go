@@ -0,0 +1,135 @@
+package conrate
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tenantShare submits 5 tasks of tenant a and 1 task of tenant b, 40 params each, to a paused executor and returns
+// the share of tenant a among the first 40 params started.
+func tenantShare(t *testing.T, opts ...Option) float64 {
+	p := NewRateLimitExecutor[int](40, opts...)
+	defer p.Stop()
+
+	var mu sync.Mutex
+	var started []string
+	task := func(tenant string) *Task[int] {
+		return NewTaskBuilder[int]().WithTenant(tenant).WithTaskFunc(func(context.Context, int) {
+			mu.Lock()
+			defer mu.Unlock()
+			started = append(started, tenant)
+			time.Sleep(time.Millisecond)
+		}).BuildTask(ints(40))
+	}
+	p.Pause()
+	var tasks []*Task[int]
+	for range 5 {
+		tasks = append(tasks, task("a"))
+	}
+	f := p.Submit(append(tasks, task("b"))...)
+	time.Sleep(100 * time.Millisecond)
+	p.Resume()
+	waitUntil(t, 5*time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(started) >= 40
+	})
+	f.Cancel()
+	f.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	a := 0
+	for _, tenant := range started[:40] {
+		if tenant == "a" {
+			a++
+		}
+	}
+	return float64(a) / 40
+}
+
+// TestTenantFairness expects tokens to be shared between tenants before tasks:
+//   - tenant a with 5 tasks gets about half of the tokens against tenant b with 1 task;
+//   - with WithTenantWeights a:3, b:1 tenant a gets about 75%.
+func TestTenantFairness(t *testing.T) {
+	if share := tenantShare(t); math.Abs(share-0.5) > 0.15 {
+		t.Fatalf("tenant a got %.2f of tokens, want 0.5", share)
+	}
+	if share := tenantShare(t, WithTenantWeights(map[string]int{"a": 3})); math.Abs(share-0.75) > 0.15 {
+		t.Fatalf("tenant a weighted 3 got %.2f of tokens, want 0.75", share)
+	}
+}
+
+// TestTenantFairnessBlocked expects a tenant not to lose its share to a task of its own blocked on WithMaxInFlight,
+// a ready task of the tenant taking the tokens the blocked task cannot.
+func TestTenantFairnessBlocked(t *testing.T) {
+	p := NewRateLimitExecutor[int](100)
+	defer p.Stop()
+
+	var a, b atomic.Int64
+	light := func(tenant string, started *atomic.Int64) *Task[int] {
+		return NewTaskBuilder[int]().WithTenant(tenant).WithTaskFunc(func(context.Context, int) {
+			started.Add(1)
+		}).BuildTask(ints(1000))
+	}
+	blocked := NewTaskBuilder[int]().WithTenant("a").WithWeight(10).WithMaxInFlight(1).WithTaskFunc(func(ctx context.Context, _ int) {
+		<-ctx.Done()
+	}).BuildTask(ints(10))
+	f := p.Submit(blocked, light("a", &a), light("b", &b))
+	defer f.Cancel()
+	time.Sleep(2 * time.Second)
+	if share := float64(a.Load()) / float64(a.Load()+b.Load()); math.Abs(share-0.5) > 0.15 {
+		t.Fatalf("tenant a got %.2f of the params started, %d against %d, want 0.5", share, a.Load(), b.Load())
+	}
+}
+
+// TestTenantCounter expects TenantCounter to count the params of the tenant tasks only and to count into Counter.
+func TestTenantCounter(t *testing.T) {
+	p := NewConcurrentExecutor[int](10)
+	defer p.Stop()
+
+	f := p.Submit(
+		NewTaskBuilder[int]().WithTenant("a").WithTaskFunc(func(context.Context, int) {}).BuildTask(ints(3)),
+		NewTaskBuilder[int]().WithTenant("b").WithTaskFunc(func(context.Context, int) {}).BuildTask(ints(2)),
+		NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {}).BuildTask(ints(1)),
+	)
+	if err := f.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	for tenant, want := range map[string]int64{"a": 3, "b": 2, "": 6} {
+		if got := p.TenantCounter(tenant).Completed(); got != want {
+			t.Fatalf("TenantCounter(%q).Completed() = %d, want %d", tenant, got, want)
+		}
+	}
+	assertCounterZeroPending(t, p.TenantCounter("a"))
+}
+
+// TestTenantScheduler expects NewTenantScheduler to drop a tenant once its last task is removed.
+func TestTenantScheduler(t *testing.T) {
+	s := NewTenantScheduler(nil, NewFIFOScheduler[int])
+	a, b := schedulable(1, 0), schedulable(1, 0)
+	a.tenant, b.tenant = "a", "b"
+	s.Add(a)
+	s.Add(b)
+	seen := map[*Task[int]]int{}
+	for range 100 {
+		seen[s.Next()]++
+	}
+	if seen[a] != 50 || seen[b] != 50 {
+		t.Fatalf("tenants got %d and %d tokens, want 50 each", seen[a], seen[b])
+	}
+	s.Remove(a)
+	for range 10 {
+		if task := s.Next(); task != b {
+			t.Fatalf("Next() = %v, want the task of the remaining tenant", task)
+		}
+	}
+	s.Remove(b)
+	if task := s.Next(); task != nil {
+		t.Fatalf("Next() = %v, want nil", task)
+	}
+}
@@ -0,0 +1,285 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrDuplicateNode = errors.New("duplicate workflow node")
+	ErrUnknownNode   = errors.New("unknown workflow node")
+	ErrWorkflowCycle = errors.New("workflow cycle")
+	ErrNodeSkipped   = errors.New("workflow node skipped")
+)
+
+// FailurePolicy tells what a workflow does with the nodes depending on a failed node
+type FailurePolicy int64
+
+const (
+	// SkipDependents skips every node depending on a failed node, directly or not, other nodes keep running
+	SkipDependents FailurePolicy = iota
+	// SkipRemaining skips every node not submitted yet on the first failure
+	SkipRemaining
+	// IgnoreFailures submits dependents as if the failed node succeeded
+	IgnoreFailures
+)
+
+// NodeError is one error of a workflow Future, Err is the error of the task of node Node
+type NodeError struct {
+	Node string
+	Err  error
+}
+
+func (n *NodeError) Error() string {
+	return "node " + n.Node + ": " + n.Err.Error()
+}
+
+func (n *NodeError) Unwrap() error {
+	return n.Err
+}
+
+type workflowNode[T any] struct {
+	name     string
+	task     *Task[T]
+	deps     []string
+	children []*workflowNode[T]
+	// parents is the number of parents not done yet
+	parents int
+	// waiting is true until the task is submitted or rejected
+	waiting    bool
+	stopCancel func() bool
+}
+
+// Workflow is a DAG of tasks, a task is submitted once all tasks it depends on are done.
+// Tasks of a Workflow belong to it and a Workflow can only be submitted once.
+type Workflow[T any] struct {
+	nodes  []*workflowNode[T]
+	index  map[string]*workflowNode[T]
+	policy FailurePolicy
+	err    error
+}
+
+// WithFailurePolicy sets how failed nodes affect their dependents, SkipDependents by default
+func (w *Workflow[T]) WithFailurePolicy(policy FailurePolicy) *Workflow[T] {
+	w.policy = policy
+	return w
+}
+
+// Add adds node name running task after every node in deps, deps may be added later
+func (w *Workflow[T]) Add(name string, task *Task[T], deps ...string) *Workflow[T] {
+	if _, ok := w.index[name]; ok {
+		w.err = errors.Join(w.err, fmt.Errorf("%w: %s", ErrDuplicateNode, name))
+		return w
+	}
+	n := &workflowNode[T]{name: name, task: task, deps: deps}
+	w.nodes = append(w.nodes, n)
+	w.index[name] = n
+	return w
+}
+
+// Plan is a dry run of the workflow, it returns node names by stage: a stage only depends on earlier stages and
+// nodes of a stage are in Add order. It fails on duplicate or unknown nodes and on cycles.
+func (w *Workflow[T]) Plan() ([][]string, error) {
+	if err := w.link(); err != nil {
+		return nil, err
+	}
+	parents := make(map[*workflowNode[T]]int, len(w.nodes))
+	var stage []*workflowNode[T]
+	for _, n := range w.nodes {
+		if parents[n] = len(n.deps); parents[n] == 0 {
+			stage = append(stage, n)
+		}
+	}
+	var stages [][]string
+	planned := 0
+	for len(stage) > 0 {
+		names := make([]string, 0, len(stage))
+		var next []*workflowNode[T]
+		for _, n := range stage {
+			names = append(names, n.name)
+			for _, child := range n.children {
+				if parents[child]--; parents[child] == 0 {
+					next = append(next, child)
+				}
+			}
+		}
+		planned += len(stage)
+		stages = append(stages, names)
+		stage = w.ordered(next)
+	}
+	if planned < len(w.nodes) {
+		var cycle []string
+		for _, n := range w.nodes {
+			if parents[n] > 0 {
+				cycle = append(cycle, n.name)
+			}
+		}
+		return nil, fmt.Errorf("%w: %s", ErrWorkflowCycle, strings.Join(cycle, ", "))
+	}
+	return stages, nil
+}
+
+// link resolves dependencies into children
+func (w *Workflow[T]) link() error {
+	if w.err != nil {
+		return w.err
+	}
+	for _, n := range w.nodes {
+		n.children = nil
+	}
+	for _, n := range w.nodes {
+		for _, dep := range n.deps {
+			parent, ok := w.index[dep]
+			if !ok {
+				return fmt.Errorf("%w: %s depends on %s", ErrUnknownNode, n.name, dep)
+			}
+			parent.children = append(parent.children, n)
+		}
+	}
+	return nil
+}
+
+// ordered sorts nodes in Add order
+func (w *Workflow[T]) ordered(nodes []*workflowNode[T]) []*workflowNode[T] {
+	position := func(n *workflowNode[T]) int {
+		return slices.Index(w.nodes, n)
+	}
+	slices.SortFunc(nodes, func(a, b *workflowNode[T]) int {
+		return position(a) - position(b)
+	})
+	return nodes
+}
+
+func NewWorkflow[T any]() *Workflow[T] {
+	return &Workflow[T]{index: make(map[string]*workflowNode[T])}
+}
+
+// workflowRun submits the nodes of a workflow as their parents are done
+type workflowRun[T any] struct {
+	e        *Executor[T]
+	workflow *Workflow[T]
+	mu       sync.Mutex
+}
+
+// finish releases or skips the children of n once its task is done
+func (r *workflowRun[T]) finish(n *workflowNode[T]) {
+	err := n.task.future.Error()
+	failed := err != nil && r.workflow.policy != IgnoreFailures
+	var ready, skipped []*workflowNode[T]
+	r.mu.Lock()
+	for _, child := range n.children {
+		if !child.waiting {
+			continue
+		}
+		if failed {
+			child.waiting = false
+			skipped = append(skipped, child)
+			continue
+		}
+		if child.parents--; child.parents == 0 {
+			child.waiting = false
+			ready = append(ready, child)
+		}
+	}
+	if failed && r.workflow.policy == SkipRemaining {
+		for _, other := range r.workflow.nodes {
+			if other.waiting {
+				other.waiting = false
+				skipped = append(skipped, other)
+			}
+		}
+	}
+	r.mu.Unlock()
+	for _, child := range skipped {
+		child.stopCancel()
+		r.e.reject(child.task, fmt.Errorf("%w: %s failed", ErrNodeSkipped, n.name))
+	}
+	for _, child := range ready {
+		child.stopCancel()
+		r.e.enqueue(context.Background(), child.task, true)
+	}
+}
+
+// watch rejects n if its task is canceled while waiting for its parents
+func (r *workflowRun[T]) watch(n *workflowNode[T]) {
+	n.stopCancel = context.AfterFunc(n.task.ctx, func() {
+		r.mu.Lock()
+		waiting := n.waiting
+		n.waiting = false
+		r.mu.Unlock()
+		if waiting {
+			r.e.reject(n.task, context.Cause(n.task.ctx))
+		}
+	})
+}
+
+// SubmitWorkflow submits the nodes of w without parents and every other node once all its parents are done.
+// Params of every node count as pending from now on. The returned Future fails with the joined *NodeError of failed
+// and skipped nodes, Future.Tasks holds the per node futures in Add order, canceling the Future cancels every node.
+func (e *Executor[T]) SubmitWorkflow(w *Workflow[T]) *Future {
+	if _, err := w.Plan(); err != nil {
+		return failedFuture(err)
+	}
+	r := &workflowRun[T]{e: e, workflow: w}
+	tasks := make([]*Task[T], 0, len(w.nodes))
+	accepted := make(map[*Task[T]]bool, len(w.nodes))
+	for _, n := range w.nodes {
+		tasks = append(tasks, n.task)
+	}
+	e.accept(tasks, func(task *Task[T]) {
+		accepted[task] = true
+	})
+
+	var roots []*workflowNode[T]
+	r.mu.Lock()
+	for _, n := range w.nodes {
+		n.parents = len(n.deps)
+		n.waiting = accepted[n.task]
+		switch {
+		case !n.waiting:
+			n.stopCancel = func() bool { return false }
+		case n.parents == 0:
+			n.waiting = false
+			n.stopCancel = func() bool { return false }
+			roots = append(roots, n)
+		default:
+			r.watch(n)
+		}
+	}
+	r.mu.Unlock()
+	for _, n := range w.nodes {
+		go func() {
+			<-n.task.future.Done()
+			r.finish(n)
+		}()
+	}
+	for _, n := range roots {
+		e.enqueue(context.Background(), n.task, true)
+	}
+	return workflowFuture(w)
+}
+
+// workflowFuture joins the node futures of w, wrapping their errors in *NodeError
+func workflowFuture[T any](w *Workflow[T]) *Future {
+	futures := make([]*Future, 0, len(w.nodes))
+	for _, n := range w.nodes {
+		futures = append(futures, n.task.future)
+	}
+	f := newFuture(cancelFuncsOf(futures)...)
+	f.tasks = tasksOf(futures)
+	go func() {
+		var errs []error
+		for i, future := range futures {
+			future.Wait()
+			if err := future.Error(); err != nil {
+				errs = append(errs, &NodeError{Node: w.nodes[i].name, Err: err})
+			}
+		}
+		f.resolve(errors.Join(errs...))
+	}()
+	return f
+}
@@ -0,0 +1,186 @@
+package conrate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder builds tasks recording when each node starts and finishes.
+type recorder struct {
+	events []string
+	mu     sync.Mutex
+}
+
+func (r *recorder) task(name string, fail bool) *Task[int] {
+	return NewTaskBuilder[int]().WithTaskFuncE(func(context.Context, int) error {
+		r.add("start " + name)
+		time.Sleep(10 * time.Millisecond)
+		r.add("end " + name)
+		if fail {
+			return errors.New(name + " failed")
+		}
+		return nil
+	}).BuildTask(ints(1))
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) index(event string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, e := range r.events {
+		if e == event {
+			return i
+		}
+	}
+	return -1
+}
+
+// TestWorkflow expects:
+//   - Plan to list the stages of a diamond a -> (b, c) -> d;
+//   - a node to start only after all its parents end;
+//   - the Future to succeed with one per node future in Add order.
+func TestWorkflow(t *testing.T) {
+	p := NewConcurrentExecutor[int](10)
+	defer p.Stop()
+
+	r := new(recorder)
+	w := NewWorkflow[int]().
+		Add("d", r.task("d", false), "b", "c").
+		Add("b", r.task("b", false), "a").
+		Add("c", r.task("c", false), "a").
+		Add("a", r.task("a", false))
+	stages, err := w.Plan()
+	if err != nil {
+		t.Fatalf("Plan() = %v, want nil", err)
+	}
+	if want := [][]string{{"a"}, {"b", "c"}, {"d"}}; !reflect.DeepEqual(stages, want) {
+		t.Fatalf("Plan() = %v, want %v", stages, want)
+	}
+
+	f := p.SubmitWorkflow(w)
+	if err := f.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatalf("WaitTimeout() = %v, want nil", err)
+	}
+	for _, edge := range [][2]string{{"a", "b"}, {"a", "c"}, {"b", "d"}, {"c", "d"}} {
+		if r.index("end "+edge[0]) > r.index("start "+edge[1]) {
+			t.Fatalf("%s started before %s ended: %v", edge[1], edge[0], r.events)
+		}
+	}
+	if got := len(f.Tasks()); got != 4 {
+		t.Fatalf("len(Tasks()) = %d, want 4", got)
+	}
+	if got := f.Counter().Completed(); got != 4 {
+		t.Fatalf("Completed() = %d, want 4", got)
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
+
+// TestWorkflowInvalid expects Plan and SubmitWorkflow to fail on cycles, unknown dependencies and duplicate nodes.
+func TestWorkflowInvalid(t *testing.T) {
+	p := NewConcurrentExecutor[int](10)
+	defer p.Stop()
+
+	for _, c := range []struct {
+		workflow *Workflow[int]
+		want     error
+	}{
+		{NewWorkflow[int]().Add("a", queuedTask(1), "c").Add("b", queuedTask(1), "a").Add("c", queuedTask(1), "b").Add("d", queuedTask(1)), ErrWorkflowCycle},
+		{NewWorkflow[int]().Add("a", queuedTask(1), "missing"), ErrUnknownNode},
+		{NewWorkflow[int]().Add("a", queuedTask(1)).Add("a", queuedTask(1)), ErrDuplicateNode},
+	} {
+		if _, err := c.workflow.Plan(); !errors.Is(err, c.want) {
+			t.Fatalf("Plan() = %v, want %v", err, c.want)
+		}
+		if err := p.SubmitWorkflow(c.workflow).Error(); !errors.Is(err, c.want) {
+			t.Fatalf("SubmitWorkflow() = %v, want %v", err, c.want)
+		}
+	}
+	assertCounterZeroPending(t, p.Counter())
+}
+
+// TestWorkflowFailurePolicy expects, when a fails in a -> b -> c with an independent slow d -> e:
+//   - SkipDependents to skip b and c only;
+//   - SkipRemaining to skip b, c and e, d is already running;
+//   - IgnoreFailures to run every node.
+func TestWorkflowFailurePolicy(t *testing.T) {
+	for _, c := range []struct {
+		policy  FailurePolicy
+		skipped []string
+	}{
+		{SkipDependents, []string{"b", "c"}},
+		{SkipRemaining, []string{"b", "c", "e"}},
+		{IgnoreFailures, nil},
+	} {
+		p := NewConcurrentExecutor[int](10)
+		r := new(recorder)
+		slow := NewTaskBuilder[int]().WithTaskFunc(func(context.Context, int) {
+			time.Sleep(100 * time.Millisecond)
+		}).BuildTask(ints(1))
+		w := NewWorkflow[int]().WithFailurePolicy(c.policy).
+			Add("a", r.task("a", true)).
+			Add("b", r.task("b", false), "a").
+			Add("c", r.task("c", false), "b").
+			Add("d", slow).
+			Add("e", r.task("e", false), "d")
+		f := p.SubmitWorkflow(w)
+		f.Wait()
+
+		var skipped []string
+		for _, err := range f.Error().(interface{ Unwrap() []error }).Unwrap() {
+			var nodeErr *NodeError
+			if !errors.As(err, &nodeErr) {
+				t.Fatalf("policy %d: error %v, want *NodeError", c.policy, err)
+			}
+			if errors.Is(err, ErrNodeSkipped) {
+				skipped = append(skipped, nodeErr.Node)
+			}
+		}
+		if !reflect.DeepEqual(skipped, c.skipped) {
+			t.Fatalf("policy %d: skipped %v, want %v", c.policy, skipped, c.skipped)
+		}
+		for _, name := range skipped {
+			if r.index("start "+name) >= 0 {
+				t.Fatalf("policy %d: skipped node %s started", c.policy, name)
+			}
+		}
+		assertCounterZeroPending(t, p.Counter())
+		if got := p.Counter().Canceled(); got != int64(len(c.skipped)) {
+			t.Fatalf("policy %d: Canceled() = %d, want %d", c.policy, got, len(c.skipped))
+		}
+		p.Stop()
+	}
+}
+
+// TestWorkflowCancel expects canceling the Future to cancel the running node and the nodes waiting for it.
+func TestWorkflowCancel(t *testing.T) {
+	p := NewConcurrentExecutor[int](10)
+	defer p.Stop()
+
+	blocking := NewTaskBuilder[int]().WithTaskFunc(func(ctx context.Context, _ int) {
+		<-ctx.Done()
+	}).BuildTask(ints(1))
+	w := NewWorkflow[int]().Add("a", blocking).Add("b", queuedTask(3), "a")
+	f := p.SubmitWorkflow(w)
+	waitUntil(t, 3*time.Second, func() bool { return p.Counter().Running() == 1 })
+	if got := p.Counter().Pending(); got != 3 {
+		t.Fatalf("Pending() = %d, want 3", got)
+	}
+
+	f.Cancel()
+	if err := f.WaitTimeout(3 * time.Second); !errors.Is(err, context.Canceled) {
+		t.Fatalf("WaitTimeout() = %v, want context.Canceled", err)
+	}
+	waitCounterSettled(t, p.Counter(), 3*time.Second)
+	if got := p.Counter().Canceled(); got != 3 {
+		t.Fatalf("Canceled() = %d, want 3", got)
+	}
+}